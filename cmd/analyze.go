@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// clusterOnlyFlags are root flags that don't apply when analyzing captures
+var clusterOnlyFlags = []string{"load", "watch", "clear-cache"}
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <file>...",
+	Short: "Analyze one or more saved log captures together",
+	Long: "Load captures saved with --save, merge them into a single timeline ordered by timestamp and analyze them together. " +
+		"When several captures are given, each entry is tagged with the name of the file it came from. " +
+		"Accepts the same filtering, output and analysis flags as the root command.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loadPaths = args
+		return rootCmd.RunE(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+// registerAnalyzeFlags shares the root's output and analysis flags with the
+// analyze command. It's called once the flags are defined.
+func registerAnalyzeFlags() {
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if !slices.Contains(clusterOnlyFlags, flag.Name) {
+			analyzeCmd.Flags().AddFlag(flag)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// completionTimeout bounds the API calls made while completing a flag, so a
+// slow or unreachable cluster doesn't hang the shell
+const completionTimeout = 5 * time.Second
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate the autocompletion script for the specified shell",
+	Long: `Generate the autocompletion script for hallucino for the specified shell.
+Besides flags, --namespace, --pod and --context complete with names from the cluster.
+
+To load completions in the current shell session:
+
+  bash:       source <(hallucino completion bash)
+  zsh:        source <(hallucino completion zsh)
+  fish:       hallucino completion fish | source
+  powershell: hallucino completion powershell | Out-String | Invoke-Expression
+
+To load them for every new session, write the script to your shell's
+completion directory, e.g. "hallucino completion bash > /etc/bash_completion.d/hallucino".`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(out, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(out)
+		case "fish":
+			return rootCmd.GenFishCompletion(out, true)
+		case "powershell":
+			return rootCmd.GenPowerShellCompletionWithDesc(out)
+		}
+		return fmt.Errorf("unsupported shell %q", args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+// registerFlagCompletions completes flags naming cluster objects. It's called
+// once the flags are defined.
+func registerFlagCompletions() {
+	for flag, complete := range map[string]func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective){
+		"namespace": completeNamespaces,
+		"pod":       completePods,
+		"context":   completeContexts,
+	} {
+		if err := rootCmd.RegisterFlagCompletionFunc(flag, complete); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// completeNamespaces lists the cluster's namespaces
+func completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	client, err := createK8sClient()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	list, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		names = append(names, ns.Name)
+	}
+	return matchingNames(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePods lists the pods in the first --namespace, falling back to the
+// kube-context namespace and then "default"
+func completePods(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	namespace := metav1.NamespaceDefault
+	if len(namespaces) > 0 {
+		namespace = namespaces[0]
+	} else if ns := contextNamespace(); ns != "" {
+		namespace = ns
+	}
+
+	client, err := createK8sClient()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	list, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, p := range list.Items {
+		names = append(names, p.Name)
+	}
+	return matchingNames(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeContexts lists the contexts in the kubeconfig
+func completeContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	raw, err := kubeClientConfig().RawConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := make([]string, 0, len(raw.Contexts))
+	for name := range raw.Contexts {
+		names = append(names, name)
+	}
+	return matchingNames(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// matchingNames returns the sorted names starting with prefix
+func matchingNames(names []string, prefix string) []string {
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
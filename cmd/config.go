@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// defaultConfigName is the config file read from the home directory when
+// --config isn't given
+const defaultConfigName = ".hallucino.yaml"
+
+// configFile is the --config path
+var configFile string
+
+// applyConfigFile sets flags the user didn't pass from the config file, so
+// flags override file values which override built-in defaults. Keys are flag
+// names, e.g. "max-pods: 10" or "timeout: 5m". A missing default file is ignored,
+// but a missing --config file is an error.
+func applyConfigFile(cmd *cobra.Command) error {
+	v := viper.New()
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			// Without a home directory there's no default file to read
+			return nil
+		}
+		path := filepath.Join(home, defaultConfigName)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		v.SetConfigFile(path)
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var errs []error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "config" || flag.Changed || !v.IsSet(flag.Name) {
+			return
+		}
+		if err := setFlagFromConfig(cmd.Flags(), flag, v); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s in %s: %w", flag.Name, v.ConfigFileUsed(), err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// setFlagFromConfig sets a flag from its config value. Lists replace the
+// flag's default rather than appending to it.
+func setFlagFromConfig(flags *pflag.FlagSet, flag *pflag.Flag, v *viper.Viper) error {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		if err := slice.Replace(v.GetStringSlice(flag.Name)); err != nil {
+			return err
+		}
+		flag.Changed = true
+		return nil
+	}
+	return flags.Set(flag.Name, v.GetString(flag.Name))
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"hallucino/internal/analysis"
+	"hallucino/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Show critical events that are new in a log capture",
+	Long: "Compare two captures saved with --save and print the critical events in the new one that don't appear in the old one. " +
+		"Events are compared with timestamps, IDs and numbers stripped, so only genuinely new errors are reported.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldPath, newPath := args[0], args[1]
+
+		baseline, err := storage.LoadFromFile(oldPath)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", oldPath, err)
+		}
+		current, err := storage.LoadFromFile(newPath)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", newPath, err)
+		}
+
+		events := analysis.NewCriticalEvents(
+			analysis.NewLogAnalyzer(baseline.GetLogs()),
+			analysis.NewLogAnalyzer(current.GetLogs()),
+		)
+		if len(events) == 0 {
+			fmt.Fprintf(out, "No critical events in %s that aren't in %s\n", newPath, oldPath)
+			return nil
+		}
+
+		fmt.Fprintf(out, "%d critical event(s) in %s that aren't in %s:\n", len(events), newPath, oldPath)
+		diff := storage.NewLogStorage()
+		diff.SetTimestampFormat(tsFormat)
+		diff.SetSeverityFunc(printSeverity)
+		for _, log := range events {
+			diff.AddLog(log)
+		}
+		diff.PrettyPrintLogs(out)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
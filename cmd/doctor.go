@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"hallucino/internal/analysis"
+	"hallucino/internal/k8s"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// doctorTimeout bounds each check, so an unreachable cluster or endpoint fails
+// its check instead of hanging the command
+const doctorTimeout = 15 * time.Second
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the connection to Kubernetes and OpenAI before a run",
+	Long: "Check that the Kubernetes API server is reachable, that pods can be listed and their logs read in the target namespaces, " +
+		"and that OpenAI answers a minimal request with the configured credentials and deployment. " +
+		"Uses the same --context, --namespace and OpenAI settings as a run, and fails when any check does.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		checks := &checklist{w: out}
+
+		if client := checkKubernetes(cmd.Context(), checks); client != nil {
+			for _, namespace := range doctorNamespaces() {
+				checkNamespace(cmd.Context(), checks, client, namespace)
+			}
+		}
+		checkOpenAI(cmd.Context(), checks)
+
+		if checks.failed > 0 {
+			return &checksError{failed: checks.failed, total: checks.total}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// checksError reports that doctor checks failed, each already explained in
+// the checklist
+type checksError struct {
+	failed int
+	total  int
+}
+
+func (e *checksError) Error() string {
+	return fmt.Sprintf("%d of %d checks failed", e.failed, e.total)
+}
+
+// checklist prints the outcome of each check as it completes
+type checklist struct {
+	w      io.Writer
+	total  int
+	failed int
+}
+
+func (c *checklist) pass(item, detail string) {
+	c.total++
+	fmt.Fprintf(c.w, "%s %s: %s\n", color.GreenString("✓"), item, detail)
+}
+
+func (c *checklist) fail(item string, err error) {
+	c.total++
+	c.failed++
+	fmt.Fprintf(c.w, "%s %s: %v\n", color.RedString("✗"), item, err)
+}
+
+// skip notes a check that couldn't run because an earlier one failed
+func (c *checklist) skip(item, reason string) {
+	fmt.Fprintf(c.w, "%s %s: skipped, %s\n", color.YellowString("-"), item, reason)
+}
+
+// checkKubernetes builds the client and asks the API server for its version,
+// returning nil when either fails
+func checkKubernetes(ctx context.Context, checks *checklist) kubernetes.Interface {
+	client, err := createK8sClient()
+	if err != nil {
+		checks.fail("Kubernetes config", err)
+		checks.skip("Namespaces", "no Kubernetes client")
+		return nil
+	}
+
+	// The discovery client has no context parameter, so bound it by timeout
+	done := make(chan error, 1)
+	var server string
+	go func() {
+		info, err := client.Discovery().ServerVersion()
+		if err == nil {
+			server = info.GitVersion
+		}
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(doctorTimeout):
+		err = fmt.Errorf("no response after %s", doctorTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		checks.fail("Kubernetes API", err)
+		checks.skip("Namespaces", "API server unreachable")
+		return nil
+	}
+
+	detail := "server " + server
+	if raw, err := kubeClientConfig().RawConfig(); err == nil {
+		name := raw.CurrentContext
+		if kubeContext != "" {
+			name = kubeContext
+		}
+		if name != "" {
+			detail += ", context " + name
+		}
+	}
+	checks.pass("Kubernetes API", detail)
+	return client
+}
+
+// doctorNamespaces returns the namespaces a run would read from, with "" for
+// all namespaces
+func doctorNamespaces() []string {
+	switch {
+	case clusterWide():
+		return []string{metav1.NamespaceAll}
+	case len(namespaces) > 0:
+		return namespaces
+	}
+	if namespace := contextNamespace(); namespace != "" {
+		return []string{namespace}
+	}
+	return []string{metav1.NamespaceDefault}
+}
+
+// checkNamespace confirms pods can be listed and their logs read
+func checkNamespace(ctx context.Context, checks *checklist, client kubernetes.Interface, namespace string) {
+	item := "Namespace " + namespace
+	if namespace == metav1.NamespaceAll {
+		item = "All namespaces"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	if _, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+		checks.fail(item+": list pods", err)
+	} else {
+		checks.pass(item+": list pods", "allowed")
+	}
+
+	logs := k8s.Permission{Namespace: namespace, Verb: "get", Resource: "pods", Subresource: "log"}
+	allowed, _, err := k8s.CheckAccess(ctx, client, logs)
+	switch {
+	case err != nil:
+		checks.fail(item+": read logs", err)
+	case !allowed:
+		checks.fail(item+": read logs", &k8s.ForbiddenError{Permission: logs})
+	default:
+		checks.pass(item+": read logs", "allowed")
+	}
+}
+
+// checkOpenAI sends a minimal completion with the configured credentials
+func checkOpenAI(ctx context.Context, checks *checklist) {
+	openaiConfig, err := openAIConfig()
+	if err != nil {
+		checks.fail("OpenAI", err)
+		return
+	}
+	openaiAnalyzer, err := analysis.NewOpenAIAnalyzer(openaiConfig)
+	if err != nil {
+		checks.fail("OpenAI", explainMissingConfig(openaiConfig.Kind, err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	model, err := openaiAnalyzer.Ping(ctx)
+	if err != nil {
+		checks.fail("OpenAI", err)
+		return
+	}
+	checks.pass("OpenAI", fmt.Sprintf("%s responded (%s)", model, openAIKind()))
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"hallucino/internal/analysis"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List Azure OpenAI deployments and check the configured one",
+	Long:  "List the model deployments on the configured Azure OpenAI resource, marking AZURE_DEPLOYMENT_NAME, and fail when it doesn't exist.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		openaiConfig, err := openAIConfig()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+		defer cancel()
+
+		deployments, err := analysis.ListDeployments(ctx, openaiConfig)
+		if err != nil {
+			return explainMissingConfig(openaiConfig.Kind, err)
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tDEPLOYMENT\tMODEL")
+		found := false
+		for _, deployment := range deployments {
+			marker := ""
+			if deployment.ID == openaiConfig.DeploymentName {
+				marker, found = "*", true
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", marker, deployment.ID, deployment.Model)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if openaiConfig.DeploymentName != "" && !found {
+			return fmt.Errorf("%w: %q", analysis.ErrDeploymentNotFound, openaiConfig.DeploymentName)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(modelsCmd)
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hallucino/internal/export"
+	"hallucino/internal/k8s"
+	"hallucino/internal/storage"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// atomicFile is written under a temporary name and renamed into place on
+// Commit, so readers never see partially written results
+type atomicFile struct {
+	*os.File
+	path string
+}
+
+// createAtomicFile starts writing path, creating its parent directories
+func createAtomicFile(path string) (*atomicFile, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Keep the temporary file beside the target so the rename stays atomic
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	return &atomicFile{File: f, path: path}, nil
+}
+
+// Commit replaces path with everything written so far
+func (f *atomicFile) Commit() error {
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// Abort discards everything written, leaving any existing file untouched
+func (f *atomicFile) Abort() {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// entryStream writes entries as NDJSON the moment they are retrieved, for
+// --stream. Writes are serialized so lines never interleave.
+type entryStream struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	entries int
+	bytes   int
+}
+
+func newEntryStream(w io.Writer) *entryStream {
+	return &entryStream{enc: json.NewEncoder(w)}
+}
+
+// Write encodes one entry as a line of JSON
+func (s *entryStream) Write(log k8s.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.enc.Encode(log); err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
+	s.entries++
+	s.bytes += len(log.LogContent)
+	return nil
+}
+
+// unsafeFileChars are replaced in --split-output file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// writeSplitOutput writes each pod's logs to <dir>/<namespace>_<pod>.log, or
+// .ndjson with --output ndjson. A name that is already taken, by an earlier
+// run or another pod, gets a numbered suffix instead of being overwritten.
+func writeSplitOutput(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create split output directory: %w", err)
+	}
+
+	ext := ".log"
+	if output == outputNDJSON {
+		ext = ".ndjson"
+	}
+
+	groups := logStore.GroupBy(storage.GroupByPod)
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		base := unsafeFileChars.ReplaceAllString(strings.Replace(key, "/", "_", 1), "_")
+		f, err := createUnique(dir, base, ext)
+		if err != nil {
+			return fmt.Errorf("failed to write split output for %s: %w", key, err)
+		}
+		if name := filepath.Base(f.Name()); name != base+ext {
+			logger.Warn("split output file already exists, writing to another name", zap.String("pod", key), zap.String("file", name))
+		}
+
+		part := storage.NewLogStorage()
+		part.SetTimestampFormat(tsFormat)
+		part.SetLabelColumns(labelColumns)
+		part.SetTemplate(lineTmpl)
+		part.SetSeverityFunc(printSeverity)
+		for _, log := range groups[key] {
+			part.AddLog(log)
+		}
+		if ext == ".ndjson" {
+			err = part.WriteNDJSON(f)
+		} else {
+			part.WriteText(f)
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Name(), err)
+		}
+	}
+
+	logger.Info("wrote split output", zap.String("dir", dir), zap.Int("pods", len(keys)))
+	return nil
+}
+
+// createUnique creates dir/base+ext, or dir/base-N+ext with the first free N
+// when that exists, never replacing an existing file
+func createUnique(dir, base, ext string) (*os.File, error) {
+	name := base + ext
+	for n := 1; ; n++ {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !errors.Is(err, os.ErrExist) {
+			return f, err
+		}
+		name = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+}
+
+// parseHeaders splits --otlp-header values into a header map
+func parseHeaders(values []string) (map[string]string, error) {
+	headers := make(map[string]string, len(values))
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid --otlp-header %q, expected key=value", value)
+		}
+		headers[strings.TrimSpace(key)] = val
+	}
+	return headers, nil
+}
+
+// exportOTLP sends the stored entries to --otlp-endpoint
+func exportOTLP(ctx context.Context) error {
+	headers, err := parseHeaders(otlpHeaders)
+	if err != nil {
+		return err
+	}
+
+	logs := logStore.GetLogs()
+	exporter := &export.OTLPExporter{Endpoint: otlpURL, Headers: headers}
+	if err := exporter.Export(ctx, logs); err != nil {
+		return fmt.Errorf("failed to export logs to %s: %w", otlpURL, err)
+	}
+	logger.Info("exported logs", zap.String("endpoint", otlpURL), zap.Int("entries", len(logs)))
+	return nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// spinnerFrames animates the progress line while streams drain
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// progress reports retrieval progress on a single, redrawn terminal line
+type progress struct {
+	mu             sync.Mutex
+	w              io.Writer
+	pods           int
+	podsDone       int
+	containersDone int
+	frame          int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// showProgress reports whether to draw progress, which is only done on an
+// interactive stderr and never for machine-readable output or with --quiet
+func showProgress() bool {
+	return !quiet && output == outputText && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// startProgress begins drawing progress for the given number of pods, returning
+// nil when progress shouldn't be shown. All methods are safe on a nil progress.
+func startProgress(pods int) *progress {
+	if !showProgress() {
+		return nil
+	}
+
+	p := &progress{
+		w:    os.Stderr,
+		pods: pods,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	// Keep the spinner moving while long streams are still draining
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.draw()
+			}
+		}
+	}()
+
+	return p
+}
+
+// containerDone records that a container's logs have been retrieved
+func (p *progress) containerDone() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.containersDone++
+	p.mu.Unlock()
+	p.draw()
+}
+
+// podDone records that all of a pod's containers have been retrieved
+func (p *progress) podDone() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.podsDone++
+	p.mu.Unlock()
+	p.draw()
+}
+
+func (p *progress) draw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.frame = (p.frame + 1) % len(spinnerFrames)
+	fmt.Fprintf(p.w, "\r\033[K%c Retrieving logs: pod %d/%d, %d containers done",
+		spinnerFrames[p.frame], p.podsDone, p.pods, p.containersDone)
+}
+
+// finish stops drawing and clears the progress line
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprint(p.w, "\r\033[K")
+}
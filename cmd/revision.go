@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"hallucino/internal/analysis"
+	"hallucino/internal/k8s"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// validateByRevision checks that --by-revision has revisions to work with and
+// an analysis to split
+func validateByRevision() error {
+	if !byRevision {
+		return nil
+	}
+	if deployment == "" && len(loadPaths) == 0 {
+		return fmt.Errorf("--by-revision requires --deployment, or captures saved with both")
+	}
+	if perPod || output == outputHTML || structured || estimateOnly {
+		return fmt.Errorf("--by-revision cannot be combined with --per-pod, --output html, --structured or --estimate-only")
+	}
+	return nil
+}
+
+// analyzeByRevision generates a separate analysis for each revision of the
+// Deployment, newest first, preceded by the critical events that only the
+// newest revision logged
+func analyzeByRevision(ctx context.Context, openaiAnalyzer *analysis.OpenAIAnalyzer, logs []k8s.LogEntry) error {
+	revisions := analysis.SplitByRevision(logs)
+	if len(revisions) == 0 {
+		return fmt.Errorf("no entries carry a Deployment revision, retrieve them with --deployment and --by-revision")
+	}
+
+	var report strings.Builder
+	newest := analysis.NewLogAnalyzer(revisions[0].Logs)
+	newest.SetExtractFields(extractFields)
+	newest.SetTimestampFormat(tsFormat)
+	if len(revisions) > 1 {
+		var older []k8s.LogEntry
+		for _, revision := range revisions[1:] {
+			older = append(older, revision.Logs...)
+		}
+		fmt.Fprintf(&report, "# Only in Revision %s\n\n", revisions[0].Revision)
+		if regressions := newest.RegressionReport(analysis.NewLogAnalyzer(older)); regressions != "" {
+			report.WriteString(regressions + "\n")
+		} else {
+			report.WriteString("No critical events that earlier revisions didn't also log.\n\n")
+		}
+	} else {
+		logger.Info("pods of only one revision were found, so there is nothing to compare it with",
+			zap.String("revision", revisions[0].Revision))
+	}
+
+	for i, revision := range revisions {
+		logAnalyzer := newest
+		if i > 0 {
+			logAnalyzer = analysis.NewLogAnalyzer(revision.Logs)
+		}
+		insights, err := generateInsights(ctx, openaiAnalyzer, logAnalyzer)
+		if err != nil {
+			return fmt.Errorf("revision %s: %w", revision.Revision, err)
+		}
+		fmt.Fprintf(&report, "# Revision %s\n\n%s\n\n", revision.Revision, insights)
+	}
+	renderMarkdown(report.String())
+
+	return notifyFindings(ctx, analysis.NewLogAnalyzer(logs).CriticalCount(), report.String())
+}
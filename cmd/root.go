@@ -1,30 +1,154 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"hallucino/internal/analysis"
+	"hallucino/internal/export"
 	"hallucino/internal/k8s"
+	hlog "hallucino/internal/logger"
+	"hallucino/internal/notify"
 	"hallucino/internal/storage"
+	"io"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/template"
+	"time"
 
 	"github.com/charmbracelet/glamour"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"golang.org/x/term"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 var (
-	kubeconfig string
-	namespace  string
-	pod        string
-	container  string
-	printRaw   bool
-	logger     *zap.Logger
-	logStore   *storage.LogStorage
+	kubeconfig     string
+	kubeContext    string
+	kubeQPS        float32
+	kubeBurst      int
+	maxPods        int
+	maxEntries     int
+	concurrency    int
+	streamTimeout  time.Duration
+	minRestarts    int
+	namespaces     []string
+	allNamespaces  bool
+	nsSelector     string
+	pod            string
+	podPrefix      string
+	containers     []string
+	printRaw       bool
+	statsOnly      bool
+	classifyOnly   bool
+	timelineBucket time.Duration
+	noAnalysis     bool
+	output         string
+	podListOptions k8s.PodListOptions
+	fieldSelector  string
+	phase          string
+	search         string
+	searchRegex    bool
+	searchICase    bool
+	noCache        bool
+	clearCache     bool
+	perPod         bool
+	byRevision     bool
+	aiConcurrency  int
+	aiQPS          float64
+	multiline      bool
+	multiPats      []string
+	multiRes       []*regexp.Regexp
+	logOptions     k8s.LogOptions
+	requireAI      bool
+	azureKey       string
+	azureEndpoint  string
+	azureDeploy    string
+	timeout        time.Duration
+	noSort         bool
+	dedupGlobal    bool
+	dedupOverlap   bool
+	minSeverity    string
+	minLevel       analysis.Severity
+	traceID        string
+	traceField     string
+	includeInit    bool
+	includePending bool
+	includeEvents  bool
+	deployment     string
+	statefulSet    string
+	daemonSet      string
+	job            string
+	cronJob        string
+	grepIncl       []string
+	grepExcl       []string
+	includeRes     []*regexp.Regexp
+	excludeRes     []*regexp.Regexp
+	skipNames      []string
+	skipPats       []string
+	skipRes        []*regexp.Regexp
+	redact         bool
+	redactPats     []string
+	redactor       *analysis.Redactor
+	denyNames      []string
+	denyPats       []string
+	denyAction     string
+	denyPolicy     *k8s.DenyPolicy
+	groupBy        string
+	promptFile     string
+	aiKind         string
+	estimateOnly   bool
+	watchInterval  time.Duration
+	tsFormat       string
+	savePath       string
+	loadPaths      []string
+	failOn         string
+	failThreshold  int
+	logLevel       string
+	verbose        bool
+	quiet          bool
+	colorMode      string
+	mdStyle        string
+	mdWidth        int
+	topPods        int
+	contextLines   int
+	errorsOnly     bool
+	contextBudget  int
+	extractFields  []string
+	labelColumns   []string
+	latencyLimit   time.Duration
+	logger         = zap.NewNop()
+	logStore       *storage.LogStorage
+	outputFile     string
+	splitDir       string
+	otlpURL        string
+	otlpHeaders    []string
+	stream         bool
+	structured     bool
+	prettyJSON     bool
+	tmplText       string
+	lineTmpl       *template.Template
+	slackURL       string
+	notifyMin      int
+	streamOut      *entryStream
+	lastRetrieval  retrievalStats
+	out            io.Writer = os.Stdout
 )
 
 var rootCmd = &cobra.Command{
@@ -33,251 +157,2212 @@ var rootCmd = &cobra.Command{
 	Long:          "A command-line tool designed to analyse Kubernetes logs, leveraging LLMs to extract insights, summarise patterns, and identify anomalies.",
 	SilenceUsage:  true,
 	SilenceErrors: true,
-	RunE: func(cmd *cobra.Command, args []string) error {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Fill in flags that weren't passed from the config file
+		if err := applyConfigFile(cmd); err != nil {
+			return err
+		}
+
 		// Initialize logger
-		logger, err := zap.NewProduction()
+		if verbose && quiet {
+			return fmt.Errorf("--quiet cannot be combined with --verbose")
+		}
+		if verbose {
+			logLevel = "debug"
+		}
+		if quiet && !cmd.Flags().Changed("log-level") {
+			// Keep warnings, dropping informational diagnostics
+			logLevel = "warn"
+		}
+		if err := applyColorMode(colorMode); err != nil {
+			return err
+		}
+		if err := validateStyle(mdStyle); err != nil {
+			return err
+		}
+		if latencyLimit < 0 {
+			return fmt.Errorf("--latency-threshold must not be negative")
+		}
+		analysis.DefaultPerformanceClassifier.Threshold = latencyLimit
+		var err error
+		logger, err = hlog.NewLogger(logLevel)
 		if err != nil {
 			return fmt.Errorf("failed to initialize logger: %w", err)
 		}
-		defer logger.Sync()
+		return nil
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		_ = logger.Sync()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Wipe cached insights and exit
+		if clearCache {
+			if err := analysis.ClearCache(); err != nil {
+				return fmt.Errorf("failed to clear cache: %w", err)
+			}
+			fmt.Fprintln(out, "Insights cache cleared")
+			return nil
+		}
+
+		// Validate output format
+		if err := validateOutputFormat(output); err != nil {
+			return err
+		}
+
+		if err := validateFailOn(failOn); err != nil {
+			return err
+		}
+
+		if err := validateGroupBy(groupBy); err != nil {
+			return err
+		}
+
+		if timelineBucket < 0 {
+			return fmt.Errorf("--timeline must not be negative")
+		}
+
+		if mdWidth < 0 {
+			return fmt.Errorf("--width must not be negative")
+		}
+
+		if output == outputHTML && (perPod || estimateOnly) {
+			return fmt.Errorf("--output html cannot be combined with --per-pod or --estimate-only")
+		}
+
+		if output == outputOTLP && (outputFile != "" || watchInterval > 0) {
+			return fmt.Errorf("--output otlp sends entries to --otlp-endpoint and cannot be combined with --output-file or --watch")
+		}
+		if _, err := parseHeaders(otlpHeaders); err != nil {
+			return err
+		}
+
+		if stream {
+			if output != outputNDJSON {
+				return fmt.Errorf("--stream requires --output ndjson")
+			}
+			if watchInterval > 0 || savePath != "" || len(loadPaths) > 0 || outputFile != "" || splitDir != "" || failOn != "" || dedupGlobal || minSeverity != "" || traceID != "" || dedupOverlap {
+				return fmt.Errorf("--stream cannot be combined with --watch, --save, --load, --output-file, --split-output, --fail-on, --dedup-global, --dedup-overlap, --min-severity or --trace")
+			}
+		}
+
+		if aiConcurrency < 1 {
+			return fmt.Errorf("--ai-concurrency must be at least 1")
+		}
+		if aiQPS < 0 {
+			return fmt.Errorf("--ai-qps must not be negative")
+		}
+
+		if notifyMin < 0 {
+			return fmt.Errorf("--notify-threshold must not be negative")
+		}
+		if slackURL != "" && (output != outputText || printRaw || statsOnly || timelineBucket > 0 || search != "" || estimateOnly) {
+			return fmt.Errorf("--slack-webhook posts the analysis and cannot be combined with --output other than text, --print-raw, --stats, --timeline, --search or --estimate-only")
+		}
+
+		if prettyJSON && output != outputJSON {
+			return fmt.Errorf("--pretty requires --output json")
+		}
+
+		if tmplText != "" {
+			if output != outputText {
+				return fmt.Errorf("--template formats text output and cannot be combined with --output %s", output)
+			}
+			tmpl, err := storage.ParseTemplate(tmplText)
+			if err != nil {
+				return fmt.Errorf("invalid --template: %w", err)
+			}
+			lineTmpl = tmpl
+		}
+
+		if err := validateByRevision(); err != nil {
+			return err
+		}
+
+		if structured && (output != outputJSON || groupBy != "" || perPod || noAnalysis || estimateOnly) {
+			return fmt.Errorf("--structured requires --output json and cannot be combined with --group-by, --per-pod, --no-analysis or --estimate-only")
+		}
+
+		if noAnalysis && (requireAI || estimateOnly) {
+			return fmt.Errorf("--no-analysis cannot be combined with --require-ai or --estimate-only")
+		}
+
+		if watchInterval < 0 {
+			return fmt.Errorf("--watch must not be negative")
+		}
+		if watchInterval > 0 && failOn != "" {
+			return fmt.Errorf("--fail-on cannot be combined with --watch")
+		}
+		if watchInterval > 0 && (savePath != "" || len(loadPaths) > 0 || splitDir != "") {
+			return fmt.Errorf("--save, --load and --split-output cannot be combined with --watch")
+		}
+
+		if search != "" {
+			if _, err := regexp.Compile(searchPattern()); err != nil {
+				return fmt.Errorf("invalid --search pattern %q: %w", search, err)
+			}
+		}
+
+		ctx, cancel, err := prepareRun(cmd)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		// Re-run the cycle until interrupted
+		if watchInterval > 0 {
+			return watchLogs(ctx)
+		}
+
+		// Read a saved capture instead of the cluster when --load is set
+		var failures *retrievalFailures
+		if len(loadPaths) > 0 {
+			err = loadLogs(loadPaths)
+		} else {
+			failures, err = collectLogs(ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		if savePath != "" {
+			if err := logStore.SaveToFile(savePath); err != nil {
+				return fmt.Errorf("failed to save logs: %w", err)
+			}
+			logger.Info("saved logs", zap.String("path", savePath), zap.Int("entries", len(logStore.GetLogs())))
+		}
+
+		// Archive each pod's logs separately
+		if splitDir != "" {
+			if err := writeSplitOutput(splitDir); err != nil {
+				return err
+			}
+		}
+
+		if err := writeOutput(ctx); err != nil {
+			return err
+		}
+
+		if len(loadPaths) == 0 {
+			printSummary()
+		}
+
+		// Exit non-zero when any pod or container failed to retrieve
+		if failures != nil {
+			return failures
+		}
+
+		// Fail the run when findings exceed the --fail-on threshold
+		return checkFailPolicy(logStore)
+	},
+}
+
+// writeOutput prints the retrieved logs in the selected format, analyzing them
+// unless a raw or export format was chosen
+func writeOutput(ctx context.Context) (err error) {
+	// Replace --output-file only once the output is complete
+	if outputFile != "" {
+		file, ferr := createAtomicFile(outputFile)
+		if ferr != nil {
+			return ferr
+		}
+		out = file
+		defer func() {
+			out = os.Stdout
+			if err != nil {
+				file.Abort()
+				return
+			}
+			err = file.Commit()
+		}()
+	}
+
+	// Pretty print logs if print-raw flag is set
+	if output == outputCSV {
+		// Export raw entries without analysis
+		if err := logStore.WriteCSV(out); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	} else if output == outputJSON && structured {
+		// Emit the AI insights for other tools instead of the entries
+		if err := writeStructuredInsights(ctx); err != nil {
+			return timeoutError(ctx, "log analysis", err)
+		}
+	} else if output == outputJSON {
+		// Export raw entries without analysis
+		if err := logStore.WriteJSON(out, groupBy, prettyJSON); err != nil {
+			return fmt.Errorf("failed to write JSON: %w", err)
+		}
+	} else if output == outputNDJSON {
+		// Entries were already written as they arrived with --stream
+		if stream {
+			return nil
+		}
+		if err := logStore.WriteNDJSON(out); err != nil {
+			return fmt.Errorf("failed to write NDJSON: %w", err)
+		}
+	} else if output == outputProm {
+		// Emit analyzer counts as metrics without calling OpenAI
+		if err := analysis.NewLogAnalyzer(logStore.GetLogs()).WritePrometheus(out); err != nil {
+			return fmt.Errorf("failed to write metrics: %w", err)
+		}
+	} else if output == outputOTLP {
+		// Send entries to the collector instead of printing them
+		if err := exportOTLP(ctx); err != nil {
+			return timeoutError(ctx, "log export", err)
+		}
+	} else if output == outputHTML {
+		// Write a standalone page to share outside the terminal
+		if err := writeHTMLReport(ctx); err != nil {
+			return timeoutError(ctx, "log analysis", err)
+		}
+	} else if search != "" {
+		// Print only matching entries with the matches highlighted
+		if err := logStore.PrettyPrintMatches(out, searchPattern()); err != nil {
+			return err
+		}
+	} else if printRaw && groupBy != "" {
+		logStore.PrettyPrintGrouped(out, groupBy)
+	} else if printRaw {
+		logStore.PrettyPrintLogs(out)
+	} else if timelineBucket > 0 {
+		// Chart error density over time without calling OpenAI
+		if err := analysis.NewLogAnalyzer(logStore.GetLogs()).WriteTimeline(out, timelineBucket); err != nil {
+			return fmt.Errorf("failed to write timeline: %w", err)
+		}
+	} else if classifyOnly {
+		// Show how each entry was classified without calling OpenAI
+		if err := analysis.NewLogAnalyzer(logStore.GetLogs()).WriteClassifications(out); err != nil {
+			return fmt.Errorf("failed to write classifications: %w", err)
+		}
+	} else if statsOnly {
+		// Print numeric breakdowns without calling OpenAI
+		if err := analysis.NewLogAnalyzer(logStore.GetLogs()).WriteStats(out); err != nil {
+			return fmt.Errorf("failed to write stats: %w", err)
+		}
+	} else {
+		// Analyze logs
+		if err := analyzeKubernetsLogs(ctx, logStore); err != nil {
+			return timeoutError(ctx, "log analysis", fmt.Errorf("log analysis failed: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// watchLogs repeats the retrieve and output cycle every --watch interval until
+// interrupted, skipping the output, and so any OpenAI request, while the
+// retrieved logs are unchanged
+func watchLogs(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var lastFingerprint string
+	for {
+		failures, err := collectLogs(ctx)
+		switch {
+		case ctx.Err() != nil:
+			// Interrupted or out of time mid-cycle
+		case err != nil:
+			logger.Error("watch cycle failed", zap.Error(err))
+		default:
+			if failures != nil {
+				logger.Error("some logs could not be retrieved", zap.Error(failures))
+			}
+
+			if fingerprint := logStore.Fingerprint(); fingerprint == lastFingerprint {
+				logger.Info("logs unchanged, skipping analysis")
+			} else if err := writeOutput(ctx); err != nil {
+				logger.Error("watch cycle failed", zap.Error(err))
+			} else {
+				lastFingerprint = fingerprint
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return timeoutError(ctx, "watch", nil)
+		case <-ticker.C:
+		}
+	}
+}
+
+// prepareRun validates the retrieval flags shared by all commands and bounds
+// the run by --timeout when set
+func prepareRun(cmd *cobra.Command) (context.Context, context.CancelFunc, error) {
+	var err error
+
+	// Validate input combinations, which a saved capture doesn't need
+	if len(loadPaths) == 0 {
+		// Fall back to the kube-context's namespace as kubectl does
+		if len(namespaces) == 0 && !clusterWide() {
+			if namespace := contextNamespace(); namespace != "" {
+				logger.Debug("using the kube-context namespace", zap.String("namespace", namespace))
+				namespaces = []string{namespace}
+			}
+		}
+
+		if clusterWide() {
+			if err := validateClusterWide(); err != nil {
+				return nil, nil, err
+			}
+		} else if err := validateInputCombinations(namespaces, pod, containers); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if podListOptions.PageSize < 0 {
+		return nil, nil, fmt.Errorf("--page-size must not be negative")
+	}
+	if logOptions.MaxBytes < 0 {
+		return nil, nil, fmt.Errorf("--max-log-bytes must not be negative")
+	}
+	if cmd.Flags().Changed("limit-bytes") && logOptions.LimitBytes <= 0 {
+		return nil, nil, fmt.Errorf("--limit-bytes must be positive")
+	}
+	if contextLines < 0 {
+		return nil, nil, fmt.Errorf("--context-lines must not be negative")
+	}
+	if errorsOnly && contextLines > 0 {
+		return nil, nil, fmt.Errorf("--errors-only sends grouped events without context and cannot be combined with --context-lines")
+	}
+	if contextBudget < 0 {
+		return nil, nil, fmt.Errorf("--context-budget must not be negative")
+	}
+	if minRestarts < 0 {
+		return nil, nil, fmt.Errorf("--min-restarts must not be negative")
+	}
+	if maxPods < 0 {
+		return nil, nil, fmt.Errorf("--max-pods must not be negative")
+	}
+	if maxEntries < 0 {
+		return nil, nil, fmt.Errorf("--max-entries must not be negative")
+	}
+	if concurrency <= 0 {
+		return nil, nil, fmt.Errorf("--concurrency must be positive")
+	}
+	if streamTimeout < 0 {
+		return nil, nil, fmt.Errorf("--per-stream-timeout must not be negative")
+	}
+	if kubeQPS <= 0 {
+		return nil, nil, fmt.Errorf("--qps must be positive")
+	}
+	if kubeBurst <= 0 {
+		return nil, nil, fmt.Errorf("--burst must be positive")
+	}
+
+	// Validate workload selection
+	if _, _, err := selectedWorkload(); err != nil {
+		return nil, nil, err
+	}
+
+	// Validate pod name matching
+	if podPrefix != "" && pod != "" {
+		return nil, nil, fmt.Errorf("--pod-prefix cannot be combined with --pod")
+	}
+	if podGlob() {
+		if _, err := path.Match(pod, ""); err != nil {
+			return nil, nil, fmt.Errorf("invalid --pod pattern %q: %w", pod, err)
+		}
+	}
+
+	// Combine --field-selector and --phase
+	if podListOptions.FieldSelector, err = podFieldSelector(fieldSelector, phase); err != nil {
+		return nil, nil, err
+	}
+
+	// Compile content filters
+	if includeRes, err = compilePatterns("--grep", grepIncl); err != nil {
+		return nil, nil, err
+	}
+	if excludeRes, err = compilePatterns("--grep-exclude", grepExcl); err != nil {
+		return nil, nil, err
+	}
+
+	// Parse the severity threshold
+	if minSeverity != "" {
+		if minLevel, err = analysis.ParseSeverity(minSeverity); err != nil {
+			return nil, nil, fmt.Errorf("invalid --min-severity: %w", err)
+		}
+	}
+
+	if traceID != "" && traceField == "" {
+		return nil, nil, fmt.Errorf("--trace-field must not be empty")
+	}
+
+	// Compile container exclusions
+	if skipRes, err = compilePatterns("--exclude-container-regex", skipPats); err != nil {
+		return nil, nil, err
+	}
+
+	// Compile secret redaction patterns
+	if redact {
+		if redactor, err = analysis.NewRedactor(redactPats); err != nil {
+			return nil, nil, err
+		}
+	} else if len(redactPats) > 0 {
+		return nil, nil, fmt.Errorf("--redact-pattern requires --redact")
+	}
+
+	// Build the deny policy, enforced as entries are retrieved
+	if denyPolicy, err = newDenyPolicy(); err != nil {
+		return nil, nil, err
+	}
+
+	// Compile stack trace continuation heuristics
+	if multiline {
+		patterns := multiPats
+		if len(patterns) == 0 {
+			patterns = k8s.DefaultContinuationPatterns
+		}
+		if multiRes, err = compilePatterns("--multiline-pattern", patterns); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Weakened TLS is opt-in, but shouldn't go unnoticed
+	if logOptions.InsecureSkipTLSVerifyBackend && len(loadPaths) == 0 {
+		logger.Warn("--insecure-skip-log-tls is set, log streams can't detect a kubelet serving an invalid or forged certificate")
+	}
+
+	// Bound the whole run by --timeout when set
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithCancel(cmd.Context())
+	return ctx, cancel, nil
+}
+
+// newLogStore creates log storage configured from the output flags, bounded
+// by --max-entries when set
+func newLogStore() *storage.LogStorage {
+	ls := storage.NewLogStorageWithCapacity(maxEntries)
+	ls.SetTimestampFormat(tsFormat)
+	ls.SetLabelColumns(labelColumns)
+	ls.SetTemplate(lineTmpl)
+	ls.SetSeverityFunc(printSeverity)
+	return ls
+}
+
+// printSeverity colors printed entries by their built-in classification
+func printSeverity(log k8s.LogEntry) storage.Severity {
+	switch analysis.Classify(log.LogContent) {
+	case analysis.CategoryError:
+		return storage.SeverityError
+	case analysis.CategoryWarning:
+		return storage.SeverityWarning
+	}
+	return storage.SeverityNone
+}
+
+// warnDropped notes when --max-entries discarded the oldest entries
+func warnDropped() {
+	if dropped := logStore.Dropped(); dropped > 0 {
+		logger.Warn("dropped oldest log entries to stay within --max-entries",
+			zap.Int("dropped", dropped), zap.Int("max_entries", maxEntries))
+	}
+}
+
+// loadLogs fills logStore from a capture written by --save, applying the same
+// content filters and redaction as retrieval
+func loadLogs(paths []string) error {
+	logStore = newLogStore()
+	for _, path := range paths {
+		saved, err := storage.LoadFromFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to load logs: %w", err)
+		}
+
+		for _, log := range denyPolicy.Apply(saved.GetLogs()) {
+			// Tag entries with their capture when merging several, keeping
+			// tags from captures that were themselves merged
+			if len(paths) > 1 && log.Source == "" {
+				log.Source = filepath.Base(path)
+			}
+			if log, keep := acceptLog(log); keep {
+				logStore.AddLog(log)
+			}
+		}
+	}
+	warnDropped()
+
+	// Order logs chronologically unless disabled, interleaving captures
+	if !noSort {
+		logStore.MergeByTimestamp()
+	}
+	pruneLogs()
+
+	return nil
+}
+
+// pruneLogs removes entries retrieved twice with --dedup-overlap, keeps only the
+// --trace entries, drops entries below --min-severity and collapses repeated
+// lines in each container with --dedup-global, after sorting so the earliest
+// occurrence is the one kept
+func pruneLogs() {
+	if dedupOverlap {
+		removed := logStore.DeduplicateOverlap()
+		logger.Debug("removed log entries retrieved more than once", zap.Int("removed", removed))
+	}
+	if traceID != "" {
+		extractor := analysis.NewFieldExtractor(traceField)
+		removed := logStore.Filter(func(log k8s.LogEntry) bool {
+			v, ok := extractor.Value(log.LogContent)
+			return ok && v == traceID
+		})
+		logger.Debug("removed log entries outside the trace", zap.Int("removed", removed), zap.String(traceField, traceID))
+		if entries, _ := logStore.Stats(); entries == 0 && removed > 0 {
+			logger.Warn("no log entries carry the --trace ID", zap.String("field", traceField), zap.String("trace", traceID))
+		}
+	}
+	if minSeverity != "" {
+		removed := logStore.Filter(func(log k8s.LogEntry) bool {
+			return analysis.EntrySeverity(log) >= minLevel
+		})
+		logger.Debug("removed log entries below --min-severity", zap.Int("removed", removed), zap.Stringer("min_severity", minLevel))
+	}
+	if dedupGlobal {
+		removed := logStore.DeduplicateGlobal()
+		logger.Debug("removed duplicate log entries", zap.Int("removed", removed))
+	}
+}
+
+// collectLogs retrieves logs into logStore. Partial failures are returned
+// separately so whatever was gathered can still be reported.
+func collectLogs(ctx context.Context) (*retrievalFailures, error) {
+	// Initialize log storage, reusing it between --watch cycles
+	if logStore == nil {
+		logStore = newLogStore()
+	} else {
+		logStore.Clear()
+	}
+
+	// Write entries as they arrive instead of storing them with --stream
+	if stream {
+		streamOut = newEntryStream(out)
+	}
+
+	// Create Kubernetes client
+	client, err := createK8sClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	// Retrieve logs based on input
+	var failures *retrievalFailures
+	if err := retrieveLogs(ctx, client); err != nil && !errors.As(err, &failures) {
+		return nil, timeoutError(ctx, "log retrieval", fmt.Errorf("log retrieval failed: %w", err))
+	}
+	if err := timeoutError(ctx, "log retrieval", nil); err != nil {
+		return nil, err
+	}
+	warnDropped()
+
+	// Order logs chronologically unless disabled
+	if !noSort {
+		logStore.MergeByTimestamp()
+	}
+	pruneLogs()
+
+	return failures, nil
+}
+
+func validateInputCombinations(namespaces []string, pod string, containers []string) error {
+	// If no parameters are specified, return an error with usage instructions
+	if len(namespaces) == 0 && pod == "" && len(containers) == 0 {
+		return fmt.Errorf(
+			`no parameters specified. Please provide at least a namespace.
+
+Usage examples:
+  hallucino --kubeconfig=/path/to/config --namespace my-namespace
+  hallucino --kubeconfig=/path/to/config --namespace my-namespace --pod my-pod
+  hallucino --kubeconfig=/path/to/config --namespace my-namespace --pod my-pod --container my-container`,
+		)
+	}
+
+	for _, namespace := range namespaces {
+		if namespace == "" {
+			return fmt.Errorf("--namespace must not be empty")
+		}
+	}
+
+	// Case 1: Containers specified without pods or namespace
+	if len(containers) > 0 && ((pod == "" && podPrefix == "" && !workloadSelected()) || len(namespaces) == 0) {
+		return fmt.Errorf(
+			"container must be specified with a namespace and a pod or workload. For example:\n" +
+				"  --namespace my-namespace --pod my-pod --container my-container\n" +
+				"  --namespace my-namespace --deployment my-deployment --container my-container",
+		)
+	}
+
+	// Case 2: Pod specified without namespace
+	if pod != "" && len(namespaces) == 0 {
+		return fmt.Errorf(
+			"pod must be specified with a namespace. For example:\n" +
+				"  --namespace my-namespace --pod my-pod",
+		)
+	}
+
+	// Case 3: Pod specified with several namespaces it could belong to
+	if pod != "" && len(namespaces) > 1 {
+		return fmt.Errorf("pod is ambiguous with multiple namespaces, specify a single --namespace")
+	}
+
+	return nil
+}
+
+// clusterWide reports whether namespaces are listed from the cluster rather
+// than named with --namespace
+func clusterWide() bool {
+	return allNamespaces || nsSelector != ""
+}
+
+// validateClusterWide rejects flags that need a single, named namespace
+func validateClusterWide() error {
+	if len(namespaces) > 0 {
+		return fmt.Errorf("--namespace cannot be combined with --all-namespaces or --namespace-selector")
+	}
+	if pod != "" || len(containers) > 0 {
+		return fmt.Errorf("--pod and --container must be specified with a single --namespace")
+	}
+	return nil
+}
+
+// searchPattern builds the regular expression for --search, quoting the term
+// unless --search-regex is set
+func searchPattern() string {
+	pattern := search
+	if !searchRegex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if searchICase {
+		pattern = "(?i)" + pattern
+	}
+	return pattern
+}
+
+// Supported --output formats
+const (
+	outputText   = "text"
+	outputCSV    = "csv"
+	outputJSON   = "json"
+	outputProm   = "prom"
+	outputHTML   = "html"
+	outputNDJSON = "ndjson"
+	outputOTLP   = "otlp"
+)
+
+var outputFormats = []string{outputText, outputCSV, outputJSON, outputNDJSON, outputProm, outputHTML, outputOTLP}
+
+func validateOutputFormat(format string) error {
+	for _, supported := range outputFormats {
+		if format == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format %q, expected one of: %s", format, strings.Join(outputFormats, ", "))
+}
+
+func validateGroupBy(dim string) error {
+	if dim == "" {
+		return nil
+	}
+	for _, supported := range storage.GroupByDimensions {
+		if dim == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --group-by %q, expected one of: %s", dim, strings.Join(storage.GroupByDimensions, ", "))
+}
+
+// Supported --color modes
+const (
+	colorAlways = "always"
+	colorAuto   = "auto"
+	colorNever  = "never"
+)
+
+// applyColorMode enables or disables colored output. In auto mode fatih/color
+// already honours NO_COLOR and disables color when stdout isn't a terminal, and
+// color is also disabled when writing to --output-file.
+func applyColorMode(mode string) error {
+	switch mode {
+	case colorAlways:
+		color.NoColor = false
+	case colorNever:
+		color.NoColor = true
+	case colorAuto:
+		if outputFile != "" {
+			color.NoColor = true
+		}
+	default:
+		return fmt.Errorf("invalid --color %q, expected one of: %s, %s, %s", mode, colorAlways, colorAuto, colorNever)
+	}
+	return nil
+}
+
+// defaultWrapWidth is glamour's own wrap width, used when stdout's width is unknown
+const defaultWrapWidth = 80
+
+// renderWidth returns --width, or the terminal's width when unset
+func renderWidth() int {
+	if mdWidth > 0 {
+		return mdWidth
+	}
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	return defaultWrapWidth
+}
+
+func validateStyle(style string) error {
+	if _, ok := glamour.DefaultStyles[style]; ok {
+		return nil
+	}
+	styles := make([]string, 0, len(glamour.DefaultStyles))
+	for name := range glamour.DefaultStyles {
+		styles = append(styles, name)
+	}
+	sort.Strings(styles)
+	return fmt.Errorf("unsupported --style %q, expected one of: %s", style, strings.Join(styles, ", "))
+}
+
+// Exit codes returned by Execute
+const (
+	exitError    = 1 // the run failed
+	exitFindings = 2 // findings exceeded the --fail-on threshold
+)
+
+// findingsError reports that analysis findings exceeded the --fail-on threshold
+type findingsError struct {
+	kind      string
+	count     int
+	threshold int
+}
+
+func (e *findingsError) Error() string {
+	return fmt.Sprintf("found %d %s(s), exceeding the --fail-threshold of %d", e.count, e.kind, e.threshold)
+}
+
+// failOnKinds are the values accepted by --fail-on
+var failOnKinds = []string{"error", "warning", "critical"}
+
+func validateFailOn(kind string) error {
+	if kind == "" {
+		return nil
+	}
+	for _, supported := range failOnKinds {
+		if kind == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --fail-on %q, expected one of: %s", kind, strings.Join(failOnKinds, ", "))
+}
+
+// checkFailPolicy returns a findingsError when the count selected by --fail-on
+// exceeds --fail-threshold
+func checkFailPolicy(logStorage *storage.LogStorage) error {
+	if failOn == "" {
+		return nil
+	}
+
+	logAnalyzer := analysis.NewLogAnalyzer(logStorage.GetLogs())
+	var count int
+	switch failOn {
+	case "error":
+		count = logAnalyzer.ErrorCount()
+	case "warning":
+		count = logAnalyzer.WarningCount()
+	case "critical":
+		count = logAnalyzer.CriticalCount()
+	}
+
+	if count > failThreshold {
+		return &findingsError{kind: failOn, count: count, threshold: failThreshold}
+	}
+	return nil
+}
+
+// selectedWorkload returns the workload chosen via --deployment, --statefulset
+// or --daemonset, if any
+func selectedWorkload() (string, string, error) {
+	var kind, name string
+	for _, w := range []struct{ kind, name string }{
+		{k8s.KindDeployment, deployment},
+		{k8s.KindStatefulSet, statefulSet},
+		{k8s.KindDaemonSet, daemonSet},
+		{k8s.KindJob, job},
+		{k8s.KindCronJob, cronJob},
+	} {
+		if w.name == "" {
+			continue
+		}
+		if kind != "" {
+			return "", "", fmt.Errorf("only one of --deployment, --statefulset, --daemonset, --job or --cronjob may be specified")
+		}
+		kind, name = w.kind, w.name
+	}
+
+	if kind != "" && (pod != "" || podPrefix != "") {
+		return "", "", fmt.Errorf("--%s cannot be combined with --pod or --pod-prefix", kind)
+	}
+	if kind != "" && len(namespaces) == 0 {
+		return "", "", fmt.Errorf(
+			"%s must be specified with a namespace. For example:\n"+
+				"  --namespace my-namespace --%s my-%s", kind, kind, kind,
+		)
+	}
+
+	return kind, name, nil
+}
+
+// workloadSelected reports whether pods are resolved from a workload
+func workloadSelected() bool {
+	return deployment != "" || statefulSet != "" || daemonSet != "" || job != "" || cronJob != ""
+}
+
+// jobSelected reports whether logs come from the pods of a Job or CronJob
+func jobSelected() bool {
+	return job != "" || cronJob != ""
+}
+
+// podPhases are the values accepted by --phase
+var podPhases = []string{"Running", "Pending", "Succeeded", "Failed", "Unknown"}
+
+// podFieldSelector builds the field selector for listing pods, translating
+// --phase into a status.phase requirement
+func podFieldSelector(selector, phase string) (string, error) {
+	if phase == "" {
+		return selector, nil
+	}
+
+	valid := false
+	for _, p := range podPhases {
+		if strings.EqualFold(phase, p) {
+			phase, valid = p, true
+			break
+		}
+	}
+	if !valid {
+		return "", fmt.Errorf("invalid --phase %q, expected one of: %s", phase, strings.Join(podPhases, ", "))
+	}
+
+	if selector == "" {
+		return "status.phase=" + phase, nil
+	}
+	return selector + ",status.phase=" + phase, nil
+}
+
+// timeoutError reports which phase was in progress when the --timeout deadline
+// expired, falling back to err otherwise
+func timeoutError(ctx context.Context, phase string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s during %s", timeout, phase)
+	}
+	return err
+}
+
+// compilePatterns compiles the regular expressions given for a repeatable flag
+func compilePatterns(flag string, patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", flag, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matchesContentFilters reports whether a log line passes --grep and --grep-exclude
+func matchesContentFilters(content string) bool {
+	for _, re := range excludeRes {
+		if re.MatchString(content) {
+			return false
+		}
+	}
+	if len(includeRes) == 0 {
+		return true
+	}
+	for _, re := range includeRes {
+		if re.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
+
+// kubeClientConfig loads kubeconfig the way kubectl does: --kubeconfig when set,
+// otherwise the files listed in $KUBECONFIG merged together, otherwise
+// ~/.kube/config, using --context instead of the current context when set
+func kubeClientConfig() clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+}
+
+// contextNamespace returns the namespace set on the current kube-context, or
+// the pod's namespace when running in-cluster, and "" when there is neither
+func contextNamespace() string {
+	clientConfig := kubeClientConfig()
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return ""
+	}
+
+	// Namespace falls back to "default" when the context doesn't set one
+	if namespace == metav1.NamespaceDefault {
+		raw, err := clientConfig.RawConfig()
+		if err != nil {
+			return ""
+		}
+		// RawConfig ignores overrides, so look up --context itself
+		current := raw.CurrentContext
+		if kubeContext != "" {
+			current = kubeContext
+		}
+		if context := raw.Contexts[current]; context == nil || context.Namespace == "" {
+			return ""
+		}
+	}
+	return namespace
+}
+
+func createK8sClient() (kubernetes.Interface, error) {
+	// Load Kubernetes configuration
+	config, err := kubeClientConfig().ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error building kubernetes config: %v", err)
+	}
+
+	// Throttle API requests client-side to stay polite on shared clusters
+	config.QPS = kubeQPS
+	config.Burst = kubeBurst
+
+	// Create Kubernetes client
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating kubernetes client: %v", err)
+	}
+
+	return client, nil
+}
+
+func retrieveLogs(ctx context.Context, client kubernetes.Interface) error {
+	// Retrieve logs based on specified parameters
+	start := time.Now()
+	var wg sync.WaitGroup
+	logChan := make(chan k8s.LogEntry, 100)
+	errorChan := make(chan error, 10)
+
+	// List the namespaces to search when not named explicitly
+	targets := namespaces
+	if clusterWide() {
+		logger.Debug("listing namespaces", zap.String("selector", nsSelector))
+		var err error
+		if targets, err = k8s.ListNamespaces(ctx, client, nsSelector); err != nil {
+			return fmt.Errorf("failed to list namespaces: %v", err)
+		}
+		if len(targets) == 0 {
+			logger.Warn("no namespaces match --namespace-selector", zap.String("selector", nsSelector))
+		}
+	}
+
+	// Never touch denied namespaces, so their logs don't leave the cluster
+	targets = allowedNamespaces(targets)
+
+	// Determine pods to retrieve logs from in every namespace before starting
+	podsByNamespace := make(map[string][]string, len(targets))
+	revisionsByNamespace := make(map[string]map[string]string, len(targets))
+	var totalPods int
+	for _, namespace := range targets {
+		pods, err := resolvePods(ctx, client, namespace)
+		if err != nil {
+			return err
+		}
+		podsByNamespace[namespace] = pods
+		totalPods += len(pods)
+
+		// Note which ReplicaSet revision each pod belongs to
+		if byRevision {
+			revisions, err := k8s.PodRevisions(ctx, client, namespace, deployment, podListOptions)
+			if err != nil {
+				return fmt.Errorf("failed to resolve revisions for deployment/%s in namespace %s: %v", deployment, namespace, err)
+			}
+			revisionsByNamespace[namespace] = revisions
+		}
+	}
+
+	// Guard against accidentally retrieving from a huge selection
+	if err := checkMaxPods(totalPods); err != nil {
+		return err
+	}
+
+	// Record the totals for the run summary
+	var scanned atomic.Int64
+	defer func() {
+		lastRetrieval = retrievalStats{pods: totalPods, containers: int(scanned.Load()), elapsed: time.Since(start)}
+	}()
+
+	// Note skipped containers once rather than per container
+	var skipped atomic.Int64
+	defer func() {
+		if n := skipped.Load(); n > 0 {
+			logger.Info("skipped containers that haven't started, pass --include-pending to try them", zap.Int64("containers", n))
+		}
+	}()
+
+	// Report progress as streams drain
+	prog := startProgress(totalPods)
+	defer prog.finish()
+
+	// Bound the open log streams across all pods, however many were selected
+	streamSlots := make(chan struct{}, concurrency)
+
+	// Concurrent log retrieval
+	for namespace, pods := range podsByNamespace {
+		for _, podName := range pods {
+			wg.Add(1)
+			go func(namespace, podName string) {
+				defer wg.Done()
+				defer prog.podDone()
+
+				// Determine containers, considering init containers when named explicitly
+				logger.Debug("listing containers", zap.String("namespace", namespace), zap.String("pod", podName))
+				podContainers, pod, err := k8s.ListContainers(ctx, client, namespace, podName, includeInit || len(containers) > 0)
+				if podGone(err) {
+					return
+				}
+				if err != nil {
+					errorChan <- &retrievalError{namespace: namespace, pod: podName, err: fmt.Errorf("failed to list containers: %w", err)}
+					return
+				}
+				if len(containers) > 0 {
+					podContainers, err = selectContainers(namespace, podName, podContainers, containers)
+					if err != nil {
+						errorChan <- &retrievalError{namespace: namespace, pod: podName, err: err}
+						return
+					}
+				}
+
+				// Drop noisy sidecars before anything is streamed
+				if len(skipNames) > 0 || len(skipRes) > 0 {
+					podContainers = excludeContainers(namespace, podName, podContainers)
+					if len(podContainers) == 0 {
+						return
+					}
+				}
+
+				// Narrow down to flapping containers, skipping healthy pods entirely
+				if minRestarts > 0 {
+					podContainers = filterRestarts(namespace, podName, podContainers)
+					if len(podContainers) == 0 {
+						return
+					}
+				}
+
+				// Keep only the labels shown by --label-columns, shared by the pod's entries
+				labels := selectLabels(pod.Labels, labelColumns)
+				revision := revisionsByNamespace[namespace][podName]
+
+				// Surface abnormal terminations, which leave no trace in the logs
+				for _, log := range denyPolicy.Apply(k8s.ContainerTerminationInfo(pod)) {
+					if containerSelected(podContainers, log.Container) {
+						log.Labels = labels
+						log.Revision = revision
+						logChan <- log
+					}
+				}
+
+				// Add the pod's events, which explain failures before or
+				// between container runs
+				if includeEvents {
+					events, err := k8s.ListPodEvents(ctx, client, namespace, podName)
+					if err != nil {
+						errorChan <- &retrievalError{namespace: namespace, pod: podName, err: fmt.Errorf("failed to list events: %w", err)}
+					}
+					for _, log := range denyPolicy.Apply(events) {
+						if log.Container == "" || containerSelected(podContainers, log.Container) {
+							log.Labels = labels
+							log.Revision = revision
+							logChan <- log
+						}
+					}
+				}
+
+				// Retrieve logs for each container, finishing the pod once all are done
+				var podWG sync.WaitGroup
+				defer podWG.Wait()
+				for _, c := range podContainers {
+					// Containers that never ran have no logs, only errors
+					if !c.Started && !includePending {
+						logger.Debug("skipping container that hasn't started",
+							zap.String("namespace", namespace),
+							zap.String("pod", podName),
+							zap.String("container", c.Name),
+						)
+						skipped.Add(1)
+						prog.containerDone()
+						continue
+					}
+
+					scanned.Add(1)
+					podWG.Add(1)
+					go func(podName string, c k8s.Container) {
+						defer podWG.Done()
+						defer prog.containerDone()
+						logger.Debug("retrieving logs",
+							zap.String("namespace", namespace),
+							zap.String("pod", podName),
+							zap.String("container", c.Name),
+						)
+
+						// Wait for a free stream slot
+						select {
+						case streamSlots <- struct{}{}:
+							defer func() { <-streamSlots }()
+						case <-ctx.Done():
+							return
+						}
+
+						// Abandon a hung stream without holding up the others
+						streamCtx := ctx
+						if streamTimeout > 0 {
+							var cancel context.CancelFunc
+							streamCtx, cancel = context.WithTimeout(ctx, streamTimeout)
+							defer cancel()
+						}
+
+						logs, err := k8s.RetrievePodLogs(streamCtx, client, namespace, podName, c.Name, logOptions)
+						if podGone(err) {
+							return
+						}
+						// Job pods restarted with OnFailure keep the failing run's
+						// log as the previous one
+						if err == nil && jobSelected() && c.Restarts > 0 {
+							previousOptions := logOptions
+							previousOptions.Previous = true
+							previous, perr := k8s.RetrievePodLogs(streamCtx, client, namespace, podName, c.Name, previousOptions)
+							if perr != nil {
+								logger.Debug("no previous log", zap.String("namespace", namespace), zap.String("pod", podName), zap.String("container", c.Name), zap.Error(perr))
+							}
+							logs = append(previous, logs...)
+						}
+						if err != nil && ctx.Err() == nil && errors.Is(streamCtx.Err(), context.DeadlineExceeded) {
+							err = fmt.Errorf("abandoned after --per-stream-timeout of %s: %w", streamTimeout, err)
+						}
+						if err != nil {
+							errorChan <- &retrievalError{
+								namespace: namespace,
+								pod:       podName,
+								container: c.Name,
+								err:       fmt.Errorf("failed to retrieve logs: %w", err),
+							}
+							// Analyze what a timed-out stream delivered before it was abandoned
+							if len(logs) == 0 {
+								return
+							}
+						}
+
+						// Merge stack traces into single events, then withhold
+						// denied entries whole before they leave this goroutine
+						logs = k8s.GroupMultiline(logs, multiRes)
+						logs = denyPolicy.Apply(logs)
+
+						// Send logs to channel, marking init container output
+						for _, log := range logs {
+							log.InitContainer = c.Init
+							log.Labels = labels
+							log.Revision = revision
+							logChan <- log
+						}
+					}(podName, c)
+				}
+			}(namespace, podName)
+		}
+	}
+
+	// Close channels when done
+	go func() {
+		wg.Wait()
+		close(logChan)
+		close(errorChan)
+	}()
+
+	// Process logs and errors with pretty printing
+	var totalLogs int
+	var errs []error
+	var streamErr error
+	var logsProcessed sync.WaitGroup
+	logsProcessed.Add(1)
+
+	go func() {
+		defer logsProcessed.Done()
+		logs, errc := logChan, errorChan
+		for logs != nil || errc != nil {
+			select {
+			case log, ok := <-logs:
+				if !ok {
+					// Logs channel closed
+					logs = nil
+					continue
+				}
+
+				// Apply content filters and redaction
+				log, keep := acceptLog(log)
+				if !keep {
+					continue
+				}
+
+				// Stream or store log, giving up on the stream once it fails
+				if streamOut != nil {
+					if streamErr == nil {
+						streamErr = streamOut.Write(log)
+					}
+				} else {
+					logStore.AddLog(log)
+				}
+				totalLogs++
+			case err, ok := <-errc:
+				if !ok {
+					// Error channel closed
+					errc = nil
+					continue
+				}
+				// Log errors as they happen and keep them for the exit status,
+				// which --quiet reduces to the final summary
+				if !quiet {
+					logger.Error("log retrieval failed", zap.Error(err))
+				}
+				errs = append(errs, err)
+			}
+		}
+	}()
+
+	// Wait for log processing to complete
+	logsProcessed.Wait()
+
+	if streamErr != nil {
+		return streamErr
+	}
+
+	if len(errs) > 0 {
+		return &retrievalFailures{errs: errs}
+	}
+
+	return nil
+}
+
+// checkMaxPods rejects retrieving from more than --max-pods pods, asking for
+// confirmation instead when run interactively
+func checkMaxPods(pods int) error {
+	if maxPods == 0 || pods <= maxPods {
+		return nil
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd())) {
+		fmt.Fprintf(os.Stderr, "Retrieve logs from %d pods, more than --max-pods=%d? [y/N] ", pods, maxPods)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.EqualFold(strings.TrimSpace(answer), "y") || strings.EqualFold(strings.TrimSpace(answer), "yes") {
+			// Don't ask again in --watch mode unless the selection grows
+			maxPods = pods
+			return nil
+		}
+	}
+
+	return fmt.Errorf("selection matches %d pods, more than --max-pods=%d; narrow it down or pass --max-pods=0 to disable the limit", pods, maxPods)
+}
+
+// retrievalStats describes the last retrieval for the run summary
+type retrievalStats struct {
+	pods       int
+	containers int
+	elapsed    time.Duration
+}
+
+// printSummary notes on stderr how much was retrieved and how long it took,
+// unless --quiet is set
+func printSummary() {
+	if quiet {
+		return
+	}
+	entries, bytes := logStore.Stats()
+	if streamOut != nil {
+		entries, bytes = streamOut.entries, streamOut.bytes
+	}
+	fmt.Fprintf(os.Stderr, "Scanned %d pods and %d containers: %d entries, %s in %s\n",
+		lastRetrieval.pods, lastRetrieval.containers, entries, formatBytes(bytes), lastRetrieval.elapsed.Round(time.Millisecond))
+}
+
+// formatBytes renders a size with a binary unit, e.g. 1.5 MiB
+func formatBytes(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// podGone reports whether err means the pod was deleted after it was listed,
+// which is expected as pods churn and so is only noted at debug level. A pod
+// named with --pod that doesn't exist is still an error.
+func podGone(err error) bool {
+	var notFound *k8s.PodNotFoundError
+	if (pod != "" && !podGlob()) || !errors.As(err, &notFound) {
+		return false
+	}
+	logger.Debug("skipping pod deleted during retrieval", zap.String("namespace", notFound.Namespace), zap.String("pod", notFound.Pod))
+	return true
+}
+
+// Supported --deny-action values
+const (
+	denyRedact = "redact"
+	denyDrop   = "drop"
+)
+
+// newDenyPolicy builds the policy from --deny-namespace, --deny-pattern and
+// --deny-action, or returns nil when nothing is denied
+func newDenyPolicy() (*k8s.DenyPolicy, error) {
+	if denyAction != denyRedact && denyAction != denyDrop {
+		return nil, fmt.Errorf("invalid --deny-action %q, expected %s or %s", denyAction, denyRedact, denyDrop)
+	}
+	if len(denyNames) == 0 && len(denyPats) == 0 {
+		return nil, nil
+	}
+
+	patterns, err := compilePatterns("--deny-pattern", denyPats)
+	if err != nil {
+		return nil, err
+	}
+	return &k8s.DenyPolicy{Namespaces: denyNames, Patterns: patterns, Drop: denyAction == denyDrop}, nil
+}
+
+// allowedNamespaces leaves out the namespaces denied by --deny-namespace
+func allowedNamespaces(targets []string) []string {
+	var allowed []string
+	for _, namespace := range targets {
+		if denyPolicy.DeniesNamespace(namespace) {
+			logger.Info("skipping namespace denied by --deny-namespace", zap.String("namespace", namespace))
+			continue
+		}
+		allowed = append(allowed, namespace)
+	}
+	return allowed
+}
+
+// acceptLog applies --grep/--grep-exclude and --redact to an entry, reporting
+// whether it should be kept
+func acceptLog(log k8s.LogEntry) (k8s.LogEntry, bool) {
+	// Drop lines filtered out by --grep/--grep-exclude, keeping markers of
+	// missing log data so the capture is still reported as incomplete
+	if !matchesContentFilters(log.LogContent) && !k8s.IsTruncation(log.LogContent) {
+		return log, false
+	}
+
+	// Mask secrets before the content is stored, printed or sent to OpenAI
+	if redactor != nil {
+		log.LogContent = redactor.Redact(log.LogContent)
+	}
+	return log, true
+}
+
+// excludeContainers drops the containers named by --exclude-container or
+// matching --exclude-container-regex
+func excludeContainers(namespace, podName string, available []k8s.Container) []k8s.Container {
+	var selected []k8s.Container
+	for _, c := range available {
+		if containerExcluded(c.Name) {
+			logger.Debug("skipping excluded container",
+				zap.String("namespace", namespace),
+				zap.String("pod", podName),
+				zap.String("container", c.Name),
+			)
+			continue
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+// containerExcluded reports whether a container is excluded from retrieval
+func containerExcluded(name string) bool {
+	for _, excluded := range skipNames {
+		if name == excluded {
+			return true
+		}
+	}
+	for _, re := range skipRes {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterRestarts keeps the containers that restarted at least --min-restarts times
+func filterRestarts(namespace, podName string, available []k8s.Container) []k8s.Container {
+	var selected []k8s.Container
+	for _, c := range available {
+		if c.Restarts < int32(minRestarts) {
+			logger.Debug("skipping container below --min-restarts",
+				zap.String("namespace", namespace),
+				zap.String("pod", podName),
+				zap.String("container", c.Name),
+				zap.Int32("restarts", c.Restarts),
+			)
+			continue
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
 
-		// Validate input combinations
-		if err := validateInputCombinations(namespace, pod, container); err != nil {
-			return err
+// selectLabels returns the named labels that the pod has, or nil when none are
+func selectLabels(podLabels map[string]string, names []string) map[string]string {
+	var selected map[string]string
+	for _, name := range names {
+		if value, ok := podLabels[name]; ok {
+			if selected == nil {
+				selected = map[string]string{}
+			}
+			selected[name] = value
+		}
+	}
+	return selected
+}
+
+// containerSelected reports whether logs are being retrieved for the named container
+func containerSelected(selected []k8s.Container, name string) bool {
+	for _, c := range selected {
+		if c.Name == name {
+			return true
 		}
+	}
+	return false
+}
+
+// resolvePods determines the pods to retrieve logs from in a namespace
+func resolvePods(ctx context.Context, client kubernetes.Interface, namespace string) ([]string, error) {
+	kind, name, err := selectedWorkload()
+	if err != nil {
+		return nil, err
+	}
 
-		// Initialize log storage
-		logStore = storage.NewLogStorage()
+	if kind != "" {
+		// Resolve the pods managed by the selected workload
+		logger.Debug("resolving workload pods", zap.String("namespace", namespace), zap.String("kind", kind), zap.String("name", name))
+		pods, err := k8s.PodsForWorkload(ctx, client, namespace, kind, name, podListOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve pods for %s/%s in namespace %s: %v", kind, name, namespace, err)
+		}
+		return pods, nil
+	}
 
-		// Create Kubernetes client
-		client, err := createK8sClient()
+	if pod == "" || podGlob() {
+		// If no specific pod, get all pods in namespace
+		logger.Debug("listing pods", zap.String("namespace", namespace))
+		pods, err := k8s.ListPods(ctx, client, namespace, podListOptions)
 		if err != nil {
-			return fmt.Errorf("failed to create Kubernetes client: %w", err)
+			return nil, fmt.Errorf("failed to list pods in namespace %s: %v", namespace, err)
 		}
+		return matchPodNames(namespace, pods), nil
+	}
+
+	return []string{pod}, nil
+}
+
+// podGlob reports whether --pod is a glob pattern, e.g. api-server-*, rather
+// than a pod name
+func podGlob() bool {
+	return strings.ContainsAny(pod, "*?[")
+}
+
+// matchPodNames keeps the pods matching --pod-prefix or a --pod glob, warning
+// when none do
+func matchPodNames(namespace string, pods []string) []string {
+	if podPrefix == "" && !podGlob() {
+		return pods
+	}
 
-		// Retrieve logs based on input
-		if err := retrieveLogs(client); err != nil {
-			return fmt.Errorf("log retrieval failed: %w", err)
+	var matched []string
+	for _, name := range pods {
+		if podPrefix != "" && strings.HasPrefix(name, podPrefix) {
+			matched = append(matched, name)
+		} else if ok, _ := path.Match(pod, name); ok {
+			matched = append(matched, name)
+		}
+	}
+	if len(matched) == 0 {
+		pattern := pod
+		if podPrefix != "" {
+			pattern = podPrefix + "*"
 		}
+		logger.Warn("no pods match the name pattern", zap.String("namespace", namespace), zap.String("pattern", pattern))
+	}
+	return matched
+}
 
-		// Pretty print logs if print-raw flag is set
-		if printRaw {
-			logStore.PrettyPrintLogs()
-		} else {
-			// Analyze logs
-			if err := analyzeKubernetsLogs(logStore); err != nil {
-				return fmt.Errorf("log analysis failed: %w", err)
+// retrievalError records a failure to list or stream logs for a pod or container
+type retrievalError struct {
+	namespace string
+	pod       string
+	container string
+	err       error
+}
+
+func (e *retrievalError) Error() string {
+	if e.container == "" {
+		return fmt.Sprintf("pod %s/%s: %v", e.namespace, e.pod, e.err)
+	}
+	return fmt.Sprintf("pod %s/%s, container %s: %v", e.namespace, e.pod, e.container, e.err)
+}
+
+func (e *retrievalError) Unwrap() error {
+	return e.err
+}
+
+// retrievalFailures aggregates the errors from a partially failed retrieval
+type retrievalFailures struct {
+	errs []error
+}
+
+func (f *retrievalFailures) Error() string {
+	pods := map[string]bool{}
+	containers := map[string]bool{}
+	for _, err := range f.errs {
+		var re *retrievalError
+		if errors.As(err, &re) {
+			// Same-named pods in different namespaces are different pods
+			pod := re.namespace + "/" + re.pod
+			pods[pod] = true
+			if re.container != "" {
+				containers[pod+"/"+re.container] = true
 			}
 		}
+	}
 
-		return nil
-	},
+	return fmt.Sprintf("log retrieval failed for %d pod(s) and %d container(s):\n%v",
+		len(pods), len(containers), errors.Join(f.errs...))
 }
 
-func validateInputCombinations(namespace, pod, container string) error {
-	// If no parameters are specified, return an error with usage instructions
-	if namespace == "" && pod == "" && container == "" {
-		return fmt.Errorf(
-			`no parameters specified. Please provide at least a namespace.
+func (f *retrievalFailures) Unwrap() []error {
+	return f.errs
+}
 
-Usage examples:
-  hallucino --kubeconfig=/path/to/config --namespace my-namespace
-  hallucino --kubeconfig=/path/to/config --namespace my-namespace --pod my-pod
-  hallucino --kubeconfig=/path/to/config --namespace my-namespace --pod my-pod --container my-container`,
-		)
+// selectContainers picks the named containers from those available in a pod,
+// failing if any of them does not exist
+func selectContainers(namespace, podName string, available []k8s.Container, names []string) ([]k8s.Container, error) {
+	byName := make(map[string]k8s.Container, len(available))
+	for _, c := range available {
+		byName[c.Name] = c
 	}
 
-	// Case 1: Container specified without pod or namespace
-	if container != "" && (pod == "" || namespace == "") {
-		return fmt.Errorf(
-			"container must be specified with both a pod and a namespace. For example:\n" +
-				"  --namespace my-namespace --pod my-pod --container my-container",
-		)
+	var selected []k8s.Container
+	var missing []string
+	for _, name := range names {
+		c, ok := byName[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		selected = append(selected, c)
+	}
+	if len(missing) > 0 {
+		return nil, &k8s.ContainerNotFoundError{Namespace: namespace, Pod: podName, Containers: missing}
 	}
 
-	// Case 2: Pod specified without namespace
-	if pod != "" && namespace == "" {
-		return fmt.Errorf(
-			"pod must be specified with a namespace. For example:\n" +
-				"  --namespace my-namespace --pod my-pod",
-		)
+	return selected, nil
+}
+
+func analyzeKubernetsLogs(ctx context.Context, logStorage *storage.LogStorage) error {
+	// Get logs from storage
+	logs := logStorage.GetLogs()
+
+	if estimateOnly {
+		return estimateTokens(logs)
 	}
 
-	return nil
+	// Create OpenAI analyzer, leaving it nil for the local report only
+	var openaiAnalyzer *analysis.OpenAIAnalyzer
+	if !noAnalysis {
+		var err error
+		if openaiAnalyzer, err = newOpenAIAnalyzer(); err != nil {
+			return err
+		}
+	}
+
+	// Show the noisiest pods ahead of the prose for quick triage
+	logAnalyzer := analysis.NewLogAnalyzer(logs)
+	if topPods > 0 && !quiet {
+		if err := logAnalyzer.WriteTopPods(out, topPods); err != nil {
+			return fmt.Errorf("failed to write top pods: %w", err)
+		}
+	}
+
+	if perPod {
+		return analyzePerPod(ctx, openaiAnalyzer, logs)
+	}
+	if byRevision {
+		return analyzeByRevision(ctx, openaiAnalyzer, logs)
+	}
+
+	// Generate insights
+	insights, err := generateInsights(ctx, openaiAnalyzer, logAnalyzer)
+	if err != nil {
+		return err
+	}
+
+	// Print or process insights
+	renderMarkdown(insights)
+
+	return notifyFindings(ctx, logAnalyzer.CriticalCount(), insights)
 }
 
-func createK8sClient() (*kubernetes.Clientset, error) {
-	// Use provided kubeconfig or default
-	if kubeconfig == "" {
-		kubeconfig = clientcmd.RecommendedHomeFile
+// writeHTMLReport writes the detailed report and, unless AI configuration is
+// missing or --no-analysis is set, the AI insights as an HTML page
+func writeHTMLReport(ctx context.Context) error {
+	logAnalyzer := analysis.NewLogAnalyzer(logStore.GetLogs())
+	logAnalyzer.SetExtractFields(extractFields)
+	logAnalyzer.SetTimestampFormat(tsFormat)
+
+	var insights string
+	if !noAnalysis {
+		openaiAnalyzer, err := newOpenAIAnalyzer()
+		if err != nil {
+			return err
+		}
+		// Without AI the page already holds everything the local report would
+		if openaiAnalyzer != nil {
+			if insights, err = generateInsights(ctx, openaiAnalyzer, logAnalyzer); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := analysis.WriteHTMLReport(out, logAnalyzer, insights); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
 	}
+	return nil
+}
 
-	// Load Kubernetes configuration
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+// writeStructuredInsights writes the AI insights as JSON. Unlike the Markdown
+// report there is no local fallback, so AI configuration is required.
+func writeStructuredInsights(ctx context.Context) error {
+	openaiConfig, err := openAIConfig()
 	if err != nil {
-		return nil, fmt.Errorf("error building kubernetes config: %v", err)
+		return err
+	}
+	openaiAnalyzer, err := analysis.NewOpenAIAnalyzer(openaiConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create OpenAI analyzer: %w", explainMissingConfig(openaiConfig.Kind, err))
 	}
 
-	// Create Kubernetes client
-	client, err := kubernetes.NewForConfig(config)
+	logAnalyzer := analysis.NewLogAnalyzer(logStore.GetLogs())
+	logAnalyzer.SetExtractFields(extractFields)
+	insights, err := openaiAnalyzer.GenerateStructuredInsights(ctx, logAnalyzer)
 	if err != nil {
-		return nil, fmt.Errorf("error creating kubernetes client: %v", err)
+		return fmt.Errorf("failed to generate insights: %w", err)
 	}
 
-	return client, nil
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(insights)
 }
 
-func retrieveLogs(client *kubernetes.Clientset) error {
-	// Retrieve logs based on specified parameters
-	var pods []string
-	var wg sync.WaitGroup
-	logChan := make(chan k8s.LogEntry, 100)
-	errorChan := make(chan error, 10)
+// newOpenAIAnalyzer creates the OpenAI analyzer from the environment. It returns
+// nil, after printing a warning, when AI configuration is missing and --require-ai
+// isn't set, so callers fall back to the local report.
+func newOpenAIAnalyzer() (*analysis.OpenAIAnalyzer, error) {
+	openaiConfig, err := openAIConfig()
+	if err != nil {
+		return nil, err
+	}
 
-	// Determine pods to retrieve logs from
-	if pod == "" {
-		// If no specific pod, get all pods in namespace
-		podList, err := k8s.ListPods(client, namespace)
-		if err != nil {
-			return fmt.Errorf("failed to list pods: %v", err)
+	openaiAnalyzer, err := analysis.NewOpenAIAnalyzer(openaiConfig)
+	var missing *analysis.MissingConfigError
+	if errors.As(err, &missing) && !requireAI {
+		// Degrade to the local report when AI credentials aren't configured,
+		// naming exactly what's missing once some of it is set
+		if len(missing.Fields) == 3 {
+			logger.Warn("AI insights skipped, set AZURE_API_KEY, AZURE_API_BASE and AZURE_DEPLOYMENT_NAME, or OPENAI_API_KEY, to enable them")
+		} else {
+			logger.Warn("AI insights skipped, " + explainMissingConfig(openaiConfig.Kind, err).Error())
 		}
-		pods = podList
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OpenAI analyzer: %w", explainMissingConfig(openaiConfig.Kind, err))
+	}
+
+	return openaiAnalyzer, nil
+}
+
+// openAIConfig builds the OpenAI configuration from the environment and flags
+func openAIConfig() (analysis.Config, error) {
+	systemPrompt, err := loadSystemPrompt(promptFile)
+	if err != nil {
+		return analysis.Config{}, err
+	}
+
+	if aiKind != "" && aiKind != analysis.KindAzure && aiKind != analysis.KindOpenAI {
+		return analysis.Config{}, fmt.Errorf("invalid --openai-kind %q, expected %s or %s", aiKind, analysis.KindAzure, analysis.KindOpenAI)
+	}
+
+	config := analysis.Config{
+		Kind:           openAIKind(),
+		APIKey:         os.Getenv("AZURE_API_KEY"),
+		Endpoint:       os.Getenv("AZURE_API_BASE"),
+		DeploymentName: os.Getenv("AZURE_DEPLOYMENT_NAME"),
+		NoCache:        noCache,
+		Logger:         logger,
+		SystemPrompt:   systemPrompt,
+		ContextLines:   contextLines,
+		ErrorsOnly:     errorsOnly,
+		TraceField:     traceField,
+		TraceID:        traceID,
+		ContextBudget:  contextBudget,
+	}
+	if config.Kind == analysis.KindOpenAI {
+		config.APIKey = os.Getenv("OPENAI_API_KEY")
+		config.Endpoint = os.Getenv("OPENAI_BASE_URL")
+		config.DeploymentName = os.Getenv("OPENAI_MODEL")
 	} else {
-		pods = []string{pod}
+		// Flags take precedence over the environment
+		if azureKey != "" {
+			config.APIKey = azureKey
+		}
+		if azureEndpoint != "" {
+			config.Endpoint = azureEndpoint
+		}
+		if azureDeploy != "" {
+			config.DeploymentName = azureDeploy
+		}
 	}
+	return config, nil
+}
 
-	// Concurrent log retrieval
-	for _, podName := range pods {
-		wg.Add(1)
-		go func(podName string) {
-			defer wg.Done()
+// configSources names where each OpenAI configuration field is set, by kind
+var configSources = map[string]map[string]string{
+	analysis.KindAzure: {
+		analysis.FieldAPIKey:         "AZURE_API_KEY or --azure-key",
+		analysis.FieldEndpoint:       "AZURE_API_BASE or --azure-endpoint",
+		analysis.FieldDeploymentName: "AZURE_DEPLOYMENT_NAME or --azure-deployment",
+	},
+	analysis.KindOpenAI: {
+		analysis.FieldAPIKey: "OPENAI_API_KEY",
+	},
+}
 
-			// Determine containers
-			var containers []string
-			if container != "" {
-				containers = []string{container}
-			} else {
-				// Get all containers in the pod
-				podContainers, err := k8s.ListContainers(client, namespace, podName)
-				if err != nil {
-					errorChan <- fmt.Errorf("failed to list containers for pod %s: %v", podName, err)
-					return
-				}
-				containers = podContainers
-			}
+// explainMissingConfig replaces the Config field names in a missing
+// configuration error with the environment variables and flags that set them
+func explainMissingConfig(kind string, err error) error {
+	var missing *analysis.MissingConfigError
+	if !errors.As(err, &missing) {
+		return err
+	}
+	sources := make([]string, 0, len(missing.Fields))
+	for _, field := range missing.Fields {
+		sources = append(sources, configSources[kind][field])
+	}
+	return fmt.Errorf("%w, set %s", analysis.ErrMissingConfig, strings.Join(sources, "; "))
+}
 
-			// Retrieve logs for each container
-			for _, containerName := range containers {
-				wg.Add(1)
-				go func(podName, containerName string) {
-					defer wg.Done()
-					logs, err := k8s.RetrievePodLogs(client, namespace, podName, containerName)
-					if err != nil {
-						errorChan <- fmt.Errorf("failed to retrieve logs for pod %s, container %s: %v",
-							podName, containerName, err)
-						return
-					}
+// openAIKind returns the --openai-kind, otherwise choosing the OpenAI API when
+// only OPENAI_API_KEY is set and Azure OpenAI in every other case
+func openAIKind() string {
+	if aiKind != "" {
+		return aiKind
+	}
+	if os.Getenv("AZURE_API_KEY") == "" && azureKey == "" && os.Getenv("OPENAI_API_KEY") != "" {
+		return analysis.KindOpenAI
+	}
+	return analysis.KindAzure
+}
 
-					// Send logs to channel
-					for _, log := range logs {
-						logChan <- log
-					}
-				}(podName, containerName)
+// estimateTokens prints the tokens each insights request would use instead of
+// calling OpenAI
+func estimateTokens(logs []k8s.LogEntry) error {
+	openaiConfig, err := openAIConfig()
+	if err != nil {
+		return err
+	}
+
+	// Mirror the requests analyzeKubernetsLogs would make, grouped by
+	// namespace/pod as analyzePerPod does
+	analyzers := map[string]*analysis.LogAnalyzer{"all pods": analysis.NewLogAnalyzer(logs)}
+	if perPod {
+		byPod := map[string][]k8s.LogEntry{}
+		for _, log := range logs {
+			pod := log.Namespace + "/" + log.PodName
+			byPod[pod] = append(byPod[pod], log)
+		}
+		analyzers = map[string]*analysis.LogAnalyzer{}
+		for podName, podLogs := range byPod {
+			if logAnalyzer := analysis.NewLogAnalyzer(podLogs); hasFindings(logAnalyzer) {
+				analyzers[podName] = logAnalyzer
 			}
-		}(podName)
+		}
 	}
 
-	// Close channels when done
-	go func() {
-		wg.Wait()
-		close(logChan)
-		close(errorChan)
-	}()
+	names := make([]string, 0, len(analyzers))
+	for name := range analyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		fmt.Fprintln(out, "No pods with critical events or performance issues.")
+		return nil
+	}
 
-	// Process logs and errors with pretty printing
-	var totalLogs int
-	var logsProcessed sync.WaitGroup
-	logsProcessed.Add(1)
+	var total analysis.Estimate
+	for _, name := range names {
+		analyzers[name].SetExtractFields(extractFields)
+		estimate, err := analysis.EstimateTokens(analyzers[name], openaiConfig)
+		if err != nil {
+			return fmt.Errorf("failed to estimate tokens: %w", err)
+		}
+		fmt.Fprintf(out, "%s: %d prompt tokens of a %d token budget, up to %d completion tokens\n", name, estimate.PromptTokens, estimate.PromptBudget, estimate.MaxCompletionTokens)
+		total.PromptTokens += estimate.PromptTokens
+		total.MaxCompletionTokens += estimate.MaxCompletionTokens
+	}
 
-	go func() {
-		defer logsProcessed.Done()
-		for {
+	if len(names) > 1 {
+		fmt.Fprintf(out, "total: %d prompt tokens, up to %d completion tokens\n", total.PromptTokens, total.MaxCompletionTokens)
+	}
+	return nil
+}
+
+// loadSystemPrompt reads the --prompt-file override, returning an empty prompt
+// when unset so the built-in one is used
+func loadSystemPrompt(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt file: %w", err)
+	}
+
+	prompt := strings.TrimSpace(string(data))
+	if prompt == "" {
+		return "", fmt.Errorf("prompt file %s is empty", path)
+	}
+	return prompt, nil
+}
+
+// analyzePerPod generates a separate section for each pod with critical events
+// or performance issues, keyed by "namespace/pod" so same-named pods in
+// different namespaces are analyzed apart
+func analyzePerPod(ctx context.Context, openaiAnalyzer *analysis.OpenAIAnalyzer, logs []k8s.LogEntry) error {
+	byPod := map[string][]k8s.LogEntry{}
+	for _, log := range logs {
+		pod := log.Namespace + "/" + log.PodName
+		byPod[pod] = append(byPod[pod], log)
+	}
+
+	podNames := make([]string, 0, len(byPod))
+	for podName := range byPod {
+		podNames = append(podNames, podName)
+	}
+	sort.Strings(podNames)
+
+	// Analyze pods concurrently, bounded by --ai-concurrency and paced by
+	// --ai-qps, collecting results by position so the report order is stable
+	podCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if aiQPS > 0 {
+		limiter = rate.NewLimiter(rate.Limit(aiQPS), 1)
+	}
+	aiSlots := make(chan struct{}, aiConcurrency)
+	results := make([]string, len(podNames))
+	errs := make([]error, len(podNames))
+	var wg sync.WaitGroup
+	for i, podName := range podNames {
+		logAnalyzer := analysis.NewLogAnalyzer(byPod[podName])
+		if !hasFindings(logAnalyzer) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, podName string) {
+			defer wg.Done()
 			select {
-			case log, ok := <-logChan:
-				if !ok {
-					// Logs channel closed
+			case aiSlots <- struct{}{}:
+				defer func() { <-aiSlots }()
+			case <-podCtx.Done():
+				return
+			}
+			// Only OpenAI requests count against the quota
+			if openaiAnalyzer != nil {
+				if err := limiter.Wait(podCtx); err != nil {
 					return
 				}
+			}
 
-				// Store log
-				logStore.AddLog(log)
-				totalLogs++
-			case err, ok := <-errorChan:
-				if !ok {
-					// Error channel closed
-					break
-				}
-				// Print errors in red
-				color.Red("Error: %v", err)
+			insights, err := generateInsights(podCtx, openaiAnalyzer, logAnalyzer)
+			if err != nil {
+				// Stop the remaining requests, one failure fails the report
+				errs[i] = fmt.Errorf("pod %s: %w", podName, err)
+				cancel()
+				return
 			}
+			results[i] = fmt.Sprintf("# Pod: %s\n\n%s\n\n", podName, insights)
+		}(i, podName)
+	}
+	wg.Wait()
+
+	// Report the failure that stopped the others rather than the
+	// cancellations it caused, or why the run as a whole stopped
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var canceled error
+	for _, err := range errs {
+		if err == nil {
+			continue
 		}
-	}()
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		if canceled == nil {
+			canceled = err
+		}
+	}
+	if canceled != nil {
+		return canceled
+	}
 
-	// Wait for log processing to complete
-	logsProcessed.Wait()
+	var report strings.Builder
+	for _, result := range results {
+		report.WriteString(result)
+	}
 
-	return nil
-}
+	if report.Len() == 0 {
+		report.WriteString("No pods with critical events or performance issues.\n")
+	}
+	renderMarkdown(report.String())
 
-func analyzeKubernetsLogs(logStorage *storage.LogStorage) error {
-	// Get logs from storage
-	logs := logStorage.GetLogs()
+	return notifyFindings(ctx, analysis.NewLogAnalyzer(logs).CriticalCount(), report.String())
+}
 
-	// Create log analyzer
-	logAnalyzer := analysis.NewLogAnalyzer(logs)
+// hasFindings reports whether a pod has anything to analyze, which with
+// --errors-only means critical events
+func hasFindings(logAnalyzer *analysis.LogAnalyzer) bool {
+	if errorsOnly {
+		return logAnalyzer.CriticalCount() > 0
+	}
+	return logAnalyzer.HasFindings()
+}
 
-	// Create OpenAI analyzer
-	openaiConfig := analysis.Config{
-		APIKey:         os.Getenv("AZURE_API_KEY"),
-		Endpoint:       os.Getenv("AZURE_API_BASE"),
-		DeploymentName: os.Getenv("AZURE_DEPLOYMENT_NAME"),
+// generateInsights asks OpenAI for insights, or returns the local report when
+// no analyzer is configured
+func generateInsights(ctx context.Context, openaiAnalyzer *analysis.OpenAIAnalyzer, logAnalyzer *analysis.LogAnalyzer) (string, error) {
+	logAnalyzer.SetExtractFields(extractFields)
+	if openaiAnalyzer == nil {
+		logAnalyzer.SetTimestampFormat(tsFormat)
+		return logAnalyzer.DetailedReport(), nil
 	}
 
-	openaiAnalyzer, err := analysis.NewOpenAIAnalyzer(openaiConfig)
+	insights, err := openaiAnalyzer.GenerateInsights(ctx, logAnalyzer)
 	if err != nil {
-		return fmt.Errorf("failed to create OpenAI analyzer: %w", err)
+		return "", fmt.Errorf("failed to generate insights: %w", err)
 	}
+	return insights, nil
+}
 
-	// Generate insights
-	insights, err := openaiAnalyzer.GenerateInsights(context.Background(), logAnalyzer)
-	if err != nil {
-		return fmt.Errorf("failed to generate insights: %w", err)
+// renderMarkdown prints Markdown rendered for the terminal, or as-is when
+// writing to --output-file
+func renderMarkdown(markdown string) {
+	if outputFile != "" {
+		fmt.Fprintln(out, markdown)
+		return
 	}
 
-	// Print or process insights
-	out, err := glamour.Render(insights, "dark")
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle(mdStyle),
+		glamour.WithWordWrap(renderWidth()),
+	)
+	var rendered string
+	if err == nil {
+		rendered, err = renderer.Render(markdown)
+	}
 	if err != nil {
-		fmt.Println("Error rendering markdown:", err)
-	} else {
-		fmt.Println(out)
+		logger.Warn("failed to render markdown, printing it as-is", zap.Error(err))
+		rendered = markdown
+	}
+	fmt.Fprintln(out, rendered)
+}
+
+// notifyFindings posts the analysis to --slack-webhook when the critical events
+// exceed --notify-threshold
+func notifyFindings(ctx context.Context, critical int, markdown string) error {
+	if slackURL == "" {
+		return nil
+	}
+	if critical <= notifyMin {
+		logger.Debug("not notifying, critical events within --notify-threshold",
+			zap.Int("critical", critical), zap.Int("threshold", notifyMin))
+		return nil
 	}
 
+	var notifier notify.Notifier = &notify.SlackNotifier{WebhookURL: slackURL}
+	msg := notify.Message{
+		Title:    fmt.Sprintf("hallucino found %d critical event(s)", critical),
+		Markdown: markdown,
+	}
+	if err := notifier.Notify(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	logger.Info("posted analysis to Slack", zap.Int("critical", critical))
 	return nil
 }
 
 func init() {
-	rootCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file")
-	rootCmd.Flags().StringVar(&namespace, "namespace", "", "Kubernetes namespace")
-	rootCmd.Flags().StringVar(&pod, "pod", "", "Specific pod name")
-	rootCmd.Flags().StringVar(&container, "container", "", "Specific container name")
+	// Retrieval and AI flags shared with subcommands
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file setting flag defaults (default $HOME/.hallucino.yaml)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Diagnostic log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable debug logging, tracing each Kubernetes and OpenAI call")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print the result on stdout and errors and warnings on stderr")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", colorAuto, "Color output: always, auto or never")
+	rootCmd.PersistentFlags().StringVar(&mdStyle, "style", "dark", "Markdown rendering style: dark, light or notty")
+	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file")
+	rootCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "Kubeconfig context to use instead of the current context")
+	rootCmd.PersistentFlags().Float32Var(&kubeQPS, "qps", rest.DefaultQPS, "Maximum sustained Kubernetes API requests per second")
+	rootCmd.PersistentFlags().IntVar(&kubeBurst, "burst", rest.DefaultBurst, "Maximum burst of Kubernetes API requests above --qps")
+	rootCmd.PersistentFlags().StringArrayVar(&namespaces, "namespace", nil, "Kubernetes namespace (repeatable)")
+	rootCmd.PersistentFlags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Retrieve logs from every namespace")
+	rootCmd.PersistentFlags().StringVar(&nsSelector, "namespace-selector", "", "Label selector restricting the namespaces searched, e.g. team=payments (implies --all-namespaces)")
+	rootCmd.PersistentFlags().StringVar(&pod, "pod", "", "Specific pod name, or a glob pattern such as api-server-* matching several")
+	rootCmd.PersistentFlags().StringVar(&podPrefix, "pod-prefix", "", "Only retrieve logs from pods whose names start with this prefix, e.g. api-server-")
+	rootCmd.PersistentFlags().StringArrayVar(&containers, "container", nil, "Specific container name (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&deployment, "deployment", "", "Retrieve logs from the pods of a Deployment")
+	rootCmd.PersistentFlags().StringVar(&statefulSet, "statefulset", "", "Retrieve logs from the pods of a StatefulSet")
+	rootCmd.PersistentFlags().StringVar(&daemonSet, "daemonset", "", "Retrieve logs from the pods of a DaemonSet")
+	rootCmd.PersistentFlags().StringVar(&job, "job", "", "Retrieve logs from the pods of a Job, including completed and failed ones")
+	rootCmd.PersistentFlags().StringVar(&cronJob, "cronjob", "", "Retrieve logs from the pods of the Jobs a CronJob spawned, including completed and failed ones")
+	rootCmd.PersistentFlags().IntVar(&maxPods, "max-pods", 50, "Refuse to retrieve logs from more pods than this, asking first in a terminal (0 for no limit)")
+	rootCmd.PersistentFlags().Int64Var(&podListOptions.PageSize, "page-size", 500, "Number of pods to fetch per list request (0 to fetch all at once)")
+	rootCmd.PersistentFlags().StringVar(&fieldSelector, "field-selector", "", "Field selector to filter pods, e.g. status.phase=Running")
+	rootCmd.PersistentFlags().StringVar(&phase, "phase", "", "Only retrieve logs from pods in this phase: Running, Pending, Succeeded, Failed or Unknown")
+	rootCmd.PersistentFlags().Int64Var(&logOptions.MaxBytes, "max-log-bytes", 0, "Maximum bytes of log to read per container (0 for unlimited)")
+	rootCmd.PersistentFlags().BoolVar(&logOptions.InsecureSkipTLSVerifyBackend, "insecure-skip-log-tls", false, "Skip verifying the kubelet's certificate when streaming logs, for clusters whose log backends have invalid certificates (insecure)")
+	rootCmd.PersistentFlags().Int64Var(&logOptions.LimitBytes, "limit-bytes", 0, "Maximum bytes of log the API server sends per container")
+	rootCmd.PersistentFlags().BoolVar(&includeInit, "include-init", true, "Include logs from init containers")
+	rootCmd.PersistentFlags().StringSliceVar(&labelColumns, "label-columns", nil, "Pod labels to capture and show as columns, e.g. app,version")
+	rootCmd.PersistentFlags().BoolVar(&includeEvents, "include-events", false, "Add each pod's Kubernetes events, such as scheduling and image pull failures, to the logs")
+	rootCmd.PersistentFlags().IntVar(&maxEntries, "max-entries", 0, "Maximum log entries to keep in memory, dropping the oldest once reached (0 for unlimited)")
+	rootCmd.PersistentFlags().IntVar(&minRestarts, "min-restarts", 0, "Only retrieve logs from containers that restarted at least this many times")
+	rootCmd.PersistentFlags().BoolVar(&includePending, "include-pending", false, "Try to retrieve logs from containers that haven't started, reporting their errors")
+	rootCmd.PersistentFlags().BoolVar(&requireAI, "require-ai", false, "Fail instead of falling back to the local report when AI configuration is missing")
+	rootCmd.PersistentFlags().StringVar(&azureKey, "azure-key", "", "Azure OpenAI API key, overriding AZURE_API_KEY")
+	rootCmd.PersistentFlags().StringVar(&azureEndpoint, "azure-endpoint", "", "Azure OpenAI endpoint, overriding AZURE_API_BASE")
+	rootCmd.PersistentFlags().StringVar(&azureDeploy, "azure-deployment", "", "Azure OpenAI deployment name, overriding AZURE_DEPLOYMENT_NAME")
+	rootCmd.PersistentFlags().StringVar(&aiKind, "openai-kind", "", "OpenAI service: azure or openai (default azure, or openai when only OPENAI_API_KEY is set)")
+	rootCmd.PersistentFlags().StringVar(&promptFile, "prompt-file", "", "File containing a system prompt to use instead of the built-in analysis instructions")
+	rootCmd.PersistentFlags().DurationVar(&latencyLimit, "latency-threshold", analysis.DefaultLatencyThreshold, "Flag lines measuring a latency, e.g. latency=4200ms, as performance issues only above this duration")
+	rootCmd.PersistentFlags().BoolVar(&errorsOnly, "errors-only", false, "Send only the critical events, grouped, to OpenAI with a prompt focused on root cause, skipping performance issues and the summary to cut token usage")
+	rootCmd.PersistentFlags().IntVar(&contextBudget, "context-budget", 0, "Maximum tokens to send to OpenAI per request, system prompt included, trimming the logs to fit (0 to fill the model's context window)")
+	rootCmd.PersistentFlags().IntVar(&contextLines, "context-lines", 0, "Number of entries from the same container to send to OpenAI before and after each critical event")
+	rootCmd.PersistentFlags().StringArrayVar(&extractFields, "extract", nil, "JSON field to show instead of the whole entry in the report and AI prompt, e.g. trace_id (repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Always query OpenAI instead of reusing cached insights")
+	rootCmd.PersistentFlags().BoolVar(&multiline, "multiline", true, "Merge multi-line stack traces into single log entries")
+	rootCmd.PersistentFlags().StringArrayVar(&multiPats, "multiline-pattern", nil, "Regular expression matching continuation lines, replacing the built-in stack trace heuristics (repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&noSort, "no-sort", false, "Keep logs in retrieval order instead of sorting by timestamp")
+	rootCmd.PersistentFlags().StringVar(&minSeverity, "min-severity", "", "Drop entries below this level before analysis and output: debug, info, warning, error or critical")
+	rootCmd.PersistentFlags().StringVar(&traceID, "trace", "", "Keep only the entries of one request, whose --trace-field has this value, time-ordered across all pods")
+	rootCmd.PersistentFlags().StringVar(&traceField, "trace-field", "trace_id", "JSON field or key=value key holding the trace ID matched by --trace")
+	rootCmd.PersistentFlags().BoolVar(&dedupOverlap, "dedup-overlap", false, "Remove entries retrieved more than once, with the same container, timestamp and content, e.g. from previous-instance logs or reconnected streams")
+	rootCmd.PersistentFlags().BoolVar(&dedupGlobal, "dedup-global", false, "Keep only the first occurrence of each repeated line in a container, wherever the repeats occur, marked with the total count, e.g. [x12]")
+	rootCmd.PersistentFlags().StringArrayVar(&grepIncl, "grep", nil, "Only keep log lines matching this regular expression (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&skipNames, "exclude-container", nil, "Never retrieve logs from containers with this name, e.g. istio-proxy (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&skipPats, "exclude-container-regex", nil, "Never retrieve logs from containers whose names match this regular expression, e.g. ^(istio-proxy|fluent-bit)$ (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&grepExcl, "grep-exclude", nil, "Drop log lines matching this regular expression (repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&redact, "redact", false, "Mask tokens, keys, emails and IP addresses in logs before printing or analysis")
+	rootCmd.PersistentFlags().StringArrayVar(&denyNames, "deny-namespace", nil, "Never retrieve logs from this namespace, e.g. one whose logs mustn't leave the cluster (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&denyPats, "deny-pattern", nil, "Withhold whole entries matching this regular expression as they're retrieved, see --deny-action (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&denyAction, "deny-action", denyRedact, "What to do with entries matching --deny-pattern: redact, replacing their content with [REDACTED: policy], or drop")
+	rootCmd.PersistentFlags().StringArrayVar(&redactPats, "redact-pattern", nil, "Additional regular expression to mask when --redact is set (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&tsFormat, "timestamp-format", "", "How to print timestamps: relative, time-only, none or a Go time layout (default RFC3339)")
+	rootCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 10, "Maximum number of container logs to stream at once")
+	rootCmd.PersistentFlags().DurationVar(&streamTimeout, "per-stream-timeout", 0, "Maximum duration to read one container's logs before abandoning it and reporting an error (0 for no limit)")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Maximum duration for the whole retrieval and analysis run (0 for no limit)")
+
+	// Output flags for the root command
 	rootCmd.Flags().BoolVar(&printRaw, "print-raw", false, "Pretty print retrieved logs")
+	rootCmd.Flags().StringVarP(&output, "output", "o", outputText, "Output format: text, csv, json, ndjson, prom, html or otlp (csv, json, ndjson, prom and otlp skip AI analysis)")
+	rootCmd.Flags().BoolVar(&structured, "structured", false, "With --output json, emit the AI insights as a JSON object with summary, issues and recommendations instead of the raw entries")
+	rootCmd.Flags().StringVar(&tmplText, "template", "", "Go text/template formatting each printed entry, with the entry's fields, e.g. '{{.Timestamp}} [{{.Container}}] {{.LogContent}}'")
+	rootCmd.Flags().BoolVar(&prettyJSON, "pretty", false, "Indent --output json for reading instead of writing it compactly on one line")
+	rootCmd.Flags().BoolVar(&stream, "stream", false, "With --output ndjson, write each entry as soon as it's retrieved instead of collecting them first")
+	rootCmd.Flags().IntVar(&mdWidth, "width", 0, "Wrap rendered Markdown at this many columns (0 to use the terminal width)")
+	rootCmd.Flags().StringVar(&otlpURL, "otlp-endpoint", export.DefaultOTLPEndpoint, "OTLP/HTTP logs URL that --output otlp sends entries to")
+	rootCmd.Flags().StringArrayVar(&otlpHeaders, "otlp-header", nil, "Header to send with --output otlp requests as key=value, e.g. Authorization=Bearer <token> (repeatable)")
+	rootCmd.Flags().StringVar(&slackURL, "slack-webhook", "", "Slack incoming webhook URL to post the analysis to when critical events exceed --notify-threshold")
+	rootCmd.Flags().IntVar(&notifyMin, "notify-threshold", 0, "Number of critical events tolerated before posting to --slack-webhook")
+	rootCmd.Flags().StringVar(&splitDir, "split-output", "", "Also write each pod's logs to <dir>/<namespace>_<pod>.log, or .ndjson with --output ndjson")
+	rootCmd.Flags().StringVar(&outputFile, "output-file", "", "Write the output to this file instead of stdout, replacing it atomically")
+	rootCmd.Flags().StringVar(&groupBy, "group-by", "", "Group --print-raw and json output by namespace, pod or container")
+	rootCmd.Flags().StringVar(&search, "search", "", "Print only logs containing this term, with matches highlighted")
+	rootCmd.Flags().BoolVar(&searchRegex, "search-regex", false, "Treat the --search term as a regular expression")
+	rootCmd.Flags().BoolVarP(&searchICase, "ignore-case", "i", false, "Match the --search term case-insensitively")
+	rootCmd.Flags().BoolVar(&noAnalysis, "no-analysis", false, "Print the local analysis report without calling OpenAI")
+	rootCmd.Flags().DurationVar(&timelineBucket, "timeline", 0, "Chart each pod's errors over time in buckets of this size, without AI analysis (1m when given without a value)")
+	rootCmd.Flags().Lookup("timeline").NoOptDefVal = analysis.DefaultTimelineBucket.String()
+	rootCmd.Flags().BoolVar(&classifyOnly, "classify-preview", false, "Print each entry with its category, severity and the rule that matched, then counts per category, without AI analysis")
+	rootCmd.Flags().BoolVar(&statsOnly, "stats", false, "Print error and warning counts per pod and container without AI analysis")
+	rootCmd.Flags().IntVar(&topPods, "top", 5, "Number of noisiest pods to list before the analysis (0 to hide)")
+	rootCmd.Flags().BoolVar(&perPod, "per-pod", false, "Generate a separate analysis for each pod with critical events or performance issues")
+	rootCmd.Flags().BoolVar(&byRevision, "by-revision", false, "Generate a separate analysis for each revision of the --deployment, listing the critical events only the newest revision logged")
+	rootCmd.Flags().IntVar(&aiConcurrency, "ai-concurrency", 4, "Maximum number of --per-pod OpenAI requests in flight at once")
+	rootCmd.Flags().Float64Var(&aiQPS, "ai-qps", 1, "Maximum --per-pod OpenAI requests started per second, to stay within rate limits (0 for no limit)")
+	rootCmd.Flags().StringVar(&failOn, "fail-on", "", "Exit with code 2 when findings of this kind exceed --fail-threshold: error, warning or critical")
+	rootCmd.Flags().IntVar(&failThreshold, "fail-threshold", 0, "Number of --fail-on findings tolerated before failing")
+	rootCmd.Flags().BoolVar(&estimateOnly, "estimate-only", false, "Print the estimated OpenAI token usage without sending the request")
+	rootCmd.Flags().DurationVar(&watchInterval, "watch", 0, "Re-retrieve and re-analyze logs at this interval until interrupted, e.g. 30s")
+	rootCmd.Flags().StringVar(&savePath, "save", "", "Save the retrieved logs to this file as NDJSON, gzip-compressed when it ends in .gz")
+	rootCmd.Flags().StringArrayVar(&loadPaths, "load", nil, "Analyze logs saved with --save instead of retrieving them from the cluster; repeat to merge several captures")
+	rootCmd.Flags().BoolVar(&clearCache, "clear-cache", false, "Remove all cached insights and exit")
+
+	registerFlagCompletions()
+	registerAnalyzeFlags()
 }
 
 // Execute adds all child commands to the root command
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		// Findings aren't a usage problem, so report them without the usage text
+		var findings *findingsError
+		if errors.As(err, &findings) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitFindings)
+		}
+
+		// Nor are failed doctor checks, which the checklist already explains
+		var checks *checksError
+		if errors.As(err, &checks) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+
+		if quiet {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+
 		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
 		fmt.Fprintln(os.Stderr, rootCmd.UsageString()) // Optionally show usage on error
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
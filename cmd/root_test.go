@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"hallucino/internal/analysis"
+	"hallucino/internal/k8s"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// truncatedCapture is a capture whose only warning-or-worse entry is an error,
+// cut short by --max-log-bytes and by a stream timeout
+func truncatedCapture() []k8s.LogEntry {
+	entry := func(content string) k8s.LogEntry {
+		return k8s.LogEntry{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:00Z", LogContent: content}
+	}
+	return []k8s.LogEntry{
+		entry(`{"level":"info","msg":"request served","trace_id":"abc"}`),
+		entry(`{"level":"error","msg":"connection refused","trace_id":"abc"}`),
+		entry("[hallucino] log truncated after 1048576 bytes (--max-log-bytes), later entries are missing"),
+		entry("[hallucino] log stream timed out after 2048 bytes, later entries are missing"),
+	}
+}
+
+func TestPruneLogsKeepsTruncations(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func()
+	}{
+		{
+			name: "min severity warning",
+			setup: func() {
+				minSeverity, minLevel = "warning", analysis.SeverityWarning
+			},
+		},
+		{
+			name: "trace",
+			setup: func() {
+				traceID, traceField = "abc", "trace_id"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(severity string, level analysis.Severity, id, field string) {
+				minSeverity, minLevel, traceID, traceField = severity, level, id, field
+			}(minSeverity, minLevel, traceID, traceField)
+			tt.setup()
+
+			logStore = newLogStore()
+			for _, log := range truncatedCapture() {
+				logStore.AddLog(log)
+			}
+			pruneLogs()
+
+			logAnalyzer := analysis.NewLogAnalyzer(logStore.GetLogs())
+			if got := len(logAnalyzer.Truncations()); got != 2 {
+				t.Errorf("Truncations() after pruning = %d entries, want 2", got)
+			}
+			if report := logAnalyzer.DetailedReport(); !strings.Contains(report, "#### Incomplete Capture") {
+				t.Errorf("DetailedReport() after pruning has no Incomplete Capture section:\n%s", report)
+			}
+		})
+	}
+}
+
+func TestAcceptLogKeepsTruncations(t *testing.T) {
+	defer func(res []*regexp.Regexp) { includeRes = res }(includeRes)
+	includeRes = []*regexp.Regexp{regexp.MustCompile("connection refused")}
+
+	var kept int
+	for _, log := range truncatedCapture() {
+		if _, ok := acceptLog(log); ok {
+			kept++
+		}
+	}
+	if kept != 3 {
+		t.Errorf("acceptLog() with --grep kept %d entries, want the match and both truncation markers", kept)
+	}
+}
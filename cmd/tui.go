@@ -0,0 +1,398 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"hallucino/internal/analysis"
+	"hallucino/internal/k8s"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse retrieved logs interactively",
+	Long:  "Retrieve logs and browse them in a scrollable, filterable terminal UI, generating insights for the entries currently shown.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel, err := prepareRun(cmd)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		failures, err := collectLogs(ctx)
+		if err != nil {
+			return err
+		}
+
+		openaiAnalyzer, err := newOpenAIAnalyzer()
+		if err != nil {
+			return err
+		}
+
+		model := newTUIModel(ctx, openaiAnalyzer, logStore.GetLogs())
+		if _, err := tea.NewProgram(model, tea.WithAltScreen()).Run(); err != nil {
+			return fmt.Errorf("failed to run TUI: %w", err)
+		}
+
+		// Exit non-zero when any pod or container failed to retrieve
+		if failures != nil {
+			return failures
+		}
+
+		return nil
+	},
+}
+
+// insightsMsg carries the result of generating insights in the background
+type insightsMsg struct {
+	insights string
+	err      error
+}
+
+// tuiModel is the Bubble Tea model for browsing log entries
+type tuiModel struct {
+	ctx            context.Context
+	openaiAnalyzer *analysis.OpenAIAnalyzer
+	logs           []k8s.LogEntry
+	categories     []analysis.Category
+
+	// Entries shown after applying the severity and text filters
+	visible  []int
+	severity analysis.Category
+	query    string
+
+	// Free-text search input while typing after "/"
+	searching bool
+	input     string
+
+	cursor int
+	offset int
+
+	analyzing bool
+	insights  string
+
+	// Rendered insights and the first of them shown in the pane
+	insightLines  []string
+	insightOffset int
+
+	width  int
+	height int
+}
+
+func newTUIModel(ctx context.Context, openaiAnalyzer *analysis.OpenAIAnalyzer, logs []k8s.LogEntry) *tuiModel {
+	m := &tuiModel{
+		ctx:            ctx,
+		openaiAnalyzer: openaiAnalyzer,
+		logs:           logs,
+		categories:     make([]analysis.Category, len(logs)),
+		width:          80,
+		height:         24,
+	}
+	for i, log := range logs {
+		m.categories[i] = analysis.Classify(log.LogContent)
+	}
+	m.applyFilters()
+	return m
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.renderInsights()
+		m.scrollToCursor()
+	case insightsMsg:
+		m.analyzing = false
+		if msg.err != nil {
+			m.insights = fmt.Sprintf("**Error:** %v", msg.err)
+		} else {
+			m.insights = msg.insights
+		}
+		m.insightOffset = 0
+		m.renderInsights()
+		m.scrollToCursor()
+	case tea.KeyMsg:
+		if m.searching {
+			return m, m.updateSearch(msg)
+		}
+		return m, m.updateBrowse(msg)
+	}
+	return m, nil
+}
+
+// updateSearch handles key presses while typing a search query
+func (m *tuiModel) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return tea.Quit
+	case tea.KeyEnter:
+		m.searching = false
+		m.query = m.input
+		m.applyFilters()
+	case tea.KeyEsc:
+		m.searching = false
+	case tea.KeyBackspace:
+		if runes := []rune(m.input); len(runes) > 0 {
+			m.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.input += string(msg.Runes)
+	}
+	return nil
+}
+
+// updateBrowse handles key presses while navigating the list
+func (m *tuiModel) updateBrowse(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "up", "k":
+		m.moveCursor(-1)
+	case "down", "j":
+		m.moveCursor(1)
+	case "pgup":
+		m.moveCursor(-m.listHeight())
+	case "pgdown":
+		m.moveCursor(m.listHeight())
+	case "home", "g":
+		m.moveCursor(-len(m.visible))
+	case "end", "G":
+		m.moveCursor(len(m.visible))
+	case "/":
+		m.searching = true
+		m.input = m.query
+	case "a":
+		m.setSeverity(analysis.CategoryNone)
+	case "e":
+		m.setSeverity(analysis.CategoryError)
+	case "w":
+		m.setSeverity(analysis.CategoryWarning)
+	case "p":
+		m.setSeverity(analysis.CategoryPerformance)
+	case "K":
+		m.scrollInsights(-1)
+	case "J":
+		m.scrollInsights(1)
+	case "ctrl+u":
+		m.scrollInsights(-m.paneHeight() / 2)
+	case "ctrl+d":
+		m.scrollInsights(m.paneHeight() / 2)
+	case "esc":
+		m.insights = ""
+		m.insightLines = nil
+		m.scrollToCursor()
+	case "enter":
+		if m.analyzing || len(m.visible) == 0 {
+			return nil
+		}
+		m.analyzing = true
+		return m.generateInsights()
+	}
+	return nil
+}
+
+// generateInsights analyzes the entries currently shown in the background
+func (m *tuiModel) generateInsights() tea.Cmd {
+	selected := make([]k8s.LogEntry, 0, len(m.visible))
+	for _, i := range m.visible {
+		selected = append(selected, m.logs[i])
+	}
+
+	return func() tea.Msg {
+		insights, err := generateInsights(m.ctx, m.openaiAnalyzer, analysis.NewLogAnalyzer(selected))
+		return insightsMsg{insights: insights, err: err}
+	}
+}
+
+func (m *tuiModel) setSeverity(severity analysis.Category) {
+	m.severity = severity
+	m.applyFilters()
+}
+
+// applyFilters recomputes the visible entries from the severity and text filters
+func (m *tuiModel) applyFilters() {
+	query := strings.ToLower(m.query)
+
+	m.visible = m.visible[:0]
+	for i, log := range m.logs {
+		if m.severity != analysis.CategoryNone && m.categories[i] != m.severity {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(log.LogContent), query) {
+			continue
+		}
+		m.visible = append(m.visible, i)
+	}
+
+	m.cursor, m.offset = 0, 0
+}
+
+func (m *tuiModel) moveCursor(delta int) {
+	m.cursor = max(0, min(m.cursor+delta, len(m.visible)-1))
+	m.scrollToCursor()
+}
+
+// scrollToCursor keeps the cursor within the visible window of the list
+func (m *tuiModel) scrollToCursor() {
+	height := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+	m.offset = max(0, m.offset)
+}
+
+// scrollInsights moves the insights pane by delta lines, stopping once the
+// last line is shown
+func (m *tuiModel) scrollInsights(delta int) {
+	last := 0
+	if pane := m.paneHeight(); len(m.insightLines) > pane {
+		// The pane's last row shows the position
+		last = len(m.insightLines) - (pane - 1)
+	}
+	m.insightOffset = max(0, min(m.insightOffset+delta, last))
+}
+
+// paneHeight is the number of rows given to the insights pane
+func (m *tuiModel) paneHeight() int {
+	if m.insights == "" && !m.analyzing {
+		return 0
+	}
+	return m.height / 2
+}
+
+// listHeight is the number of rows available for log entries
+func (m *tuiModel) listHeight() int {
+	// Leave room for the status and help lines
+	return max(1, m.height-m.paneHeight()-2)
+}
+
+func (m *tuiModel) View() string {
+	var b strings.Builder
+
+	cursorStyle := color.New(color.ReverseVideo).SprintFunc()
+	severityColors := map[analysis.Category]*color.Color{
+		analysis.CategoryError:       color.New(color.FgRed),
+		analysis.CategoryWarning:     color.New(color.FgYellow),
+		analysis.CategoryPerformance: color.New(color.FgCyan),
+		analysis.CategoryRestart:     color.New(color.FgMagenta),
+	}
+
+	// Log entries
+	height := m.listHeight()
+	for row := 0; row < height; row++ {
+		idx := m.offset + row
+		if idx >= len(m.visible) {
+			b.WriteString("\n")
+			continue
+		}
+
+		log := m.logs[m.visible[idx]]
+		line := fmt.Sprintf("%s | %s | %s",
+			log.PodName, log.Container,
+			strings.ReplaceAll(log.LogContent, "\n", " ⏎ "),
+		)
+		if tsFormat != k8s.TimestampNone {
+			line = k8s.FormatTimestamp(log.Timestamp, tsFormat) + " | " + line
+		}
+		line = truncate(line, m.width)
+
+		switch {
+		case idx == m.cursor:
+			line = cursorStyle(line)
+		case severityColors[m.categories[m.visible[idx]]] != nil:
+			line = severityColors[m.categories[m.visible[idx]]].Sprint(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	// Status line
+	severity := "all"
+	if m.severity != analysis.CategoryNone {
+		severity = string(m.severity)
+	}
+	status := fmt.Sprintf("%d/%d entries | severity: %s", len(m.visible), len(m.logs), severity)
+	if m.searching {
+		status += " | search: " + m.input + "█"
+	} else if m.query != "" {
+		status += " | search: " + m.query
+	}
+	b.WriteString(truncate(status, m.width) + "\n")
+
+	// Insights pane
+	if pane := m.paneHeight(); pane > 0 {
+		b.WriteString(m.insightsPane(pane))
+	}
+
+	// Help line
+	help := "↑/↓ move • / search • a/e/w/p severity • enter insights • esc close • q quit"
+	if len(m.insightLines) > m.paneHeight() {
+		help = "↑/↓ move • J/K or ctrl+d/ctrl+u scroll insights • esc close • q quit"
+	}
+	b.WriteString(truncate(help, m.width))
+
+	return b.String()
+}
+
+// renderInsights renders the insights as Markdown at the current width,
+// keeping the pane scrolled within them
+func (m *tuiModel) renderInsights() {
+	if m.insights == "" {
+		return
+	}
+	content := m.insights
+	renderer, err := glamour.NewTermRenderer(glamour.WithStandardStyle(mdStyle), glamour.WithWordWrap(m.width))
+	if err == nil {
+		if rendered, err := renderer.Render(m.insights); err == nil {
+			content = rendered
+		}
+	}
+	m.insightLines = strings.Split(strings.Trim(content, "\n"), "\n")
+	m.scrollInsights(0)
+}
+
+// insightsPane shows the insights from the scroll offset, clipped to the
+// given height, with the position in the last row when they don't fit
+func (m *tuiModel) insightsPane(height int) string {
+	if m.analyzing {
+		return "Generating insights..." + strings.Repeat("\n", height)
+	}
+
+	lines := m.insightLines[m.insightOffset:]
+	if len(m.insightLines) > height {
+		lines = append([]string{}, lines[:min(len(lines), height-1)]...)
+		position := fmt.Sprintf("── lines %d-%d of %d ──", m.insightOffset+1, m.insightOffset+len(lines), len(m.insightLines))
+		lines = append(lines, color.New(color.Faint).Sprint(truncate(position, m.width)))
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// truncate shortens s to at most width runes
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 || len(runes) <= width {
+		return s
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// Build information, set at build time with e.g.
+//
+//	go build -ldflags "-X hallucino/cmd.version=v1.2.0 -X hallucino/cmd.commit=$(git rev-parse --short HEAD) -X hallucino/cmd.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+// versionInfo describes the build for bug reports
+func versionInfo() string {
+	return fmt.Sprintf("hallucino %s\ncommit: %s\nbuilt: %s\ngo: %s %s/%s\n",
+		version, commit, buildDate, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version, commit and build date",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprint(out, versionInfo())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+
+	// Enable --version with the same output as the subcommand
+	rootCmd.Version = version
+	rootCmd.SetVersionTemplate(versionInfo())
+}
@@ -3,26 +3,61 @@ package analysis
 import (
 	"fmt"
 	"hallucino/internal/k8s"
+	"io"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
 )
 
-// LogAnalyzer provides methods for processing Kubernetes logs
+// Counts holds the finding tallies for a group of log entries
+type Counts struct {
+	Errors            int
+	Warnings          int
+	PerformanceIssues int
+}
+
+// podKey identifies a pod across namespaces
+type podKey struct {
+	namespace string
+	pod       string
+}
+
+// LogAnalyzer provides methods for processing Kubernetes logs. It is safe for
+// concurrent use, so entries can be added with Add while results are read.
 type LogAnalyzer struct {
+	mu                sync.RWMutex
 	logs              []k8s.LogEntry
 	criticalEvents    []k8s.LogEntry
+	criticalIndexes   []int // positions of criticalEvents in logs
 	performanceIssues []k8s.LogEntry
 	errorCount        int
 	warningCount      int
+	podStats          map[podKey]Counts
+	containerStats    map[string]Counts
+	timestampFormat   string
+	classifiers       []LineClassifier
+	extractFields     []string
+	customFindings    map[Category][]k8s.LogEntry
+	decisions         []decision     // classification of each entry in logs
+	truncations       []k8s.LogEntry // markers of missing log data
 }
 
-// NewLogAnalyzer creates a new log analyzer instance
-func NewLogAnalyzer(logs []k8s.LogEntry) *LogAnalyzer {
+// NewLogAnalyzer creates a new log analyzer instance. Custom classifiers are
+// consulted in order before the built-in ones, so they take precedence.
+func NewLogAnalyzer(logs []k8s.LogEntry, classifiers ...LineClassifier) *LogAnalyzer {
 	la := &LogAnalyzer{
-		logs:              logs,
+		// Cap the slice so Add never appends into the caller's backing array
+		logs:              logs[:len(logs):len(logs)],
 		errorCount:        0,
 		warningCount:      0,
 		criticalEvents:    []k8s.LogEntry{},
 		performanceIssues: []k8s.LogEntry{},
+		podStats:          map[podKey]Counts{},
+		containerStats:    map[string]Counts{},
+		classifiers:       append(append([]LineClassifier{}, classifiers...), DefaultClassifiers...),
+		customFindings:    map[Category][]k8s.LogEntry{},
 	}
 	la.processLogs()
 	return la
@@ -30,66 +65,462 @@ func NewLogAnalyzer(logs []k8s.LogEntry) *LogAnalyzer {
 
 // processLogs analyzes all log entries
 func (la *LogAnalyzer) processLogs() {
-	for _, log := range la.logs {
-		la.analyzeLine(log)
+	for i, log := range la.logs {
+		la.analyzeLine(i, log)
 	}
 }
 
+// Add classifies an entry and updates the running counts, so results can be
+// read as logs stream in without re-processing earlier entries
+func (la *LogAnalyzer) Add(log k8s.LogEntry) {
+	la.mu.Lock()
+	defer la.mu.Unlock()
+
+	la.logs = append(la.logs, log)
+	la.analyzeLine(len(la.logs)-1, log)
+}
+
+// Category is the classification assigned to a log line
+type Category string
+
+// Log line categories, in order of precedence
+const (
+	CategoryError       Category = "error"
+	CategoryWarning     Category = "warning"
+	CategoryPerformance Category = "performance"
+	CategoryRestart     Category = "restart"
+	CategoryNone        Category = ""
+)
+
+// LineClassifier assigns a category to a log entry, reporting whether it
+// matched. Categories other than the built-in ones are reported separately.
+type LineClassifier interface {
+	Classify(log k8s.LogEntry) (category string, matched bool)
+}
+
+// RegexClassifier assigns its category to entries whose content matches Pattern
+type RegexClassifier struct {
+	Category Category
+	Pattern  *regexp.Regexp
+}
+
+// Classify implements LineClassifier
+func (c RegexClassifier) Classify(log k8s.LogEntry) (string, bool) {
+	if c.Pattern.MatchString(log.LogContent) {
+		return string(c.Category), true
+	}
+	return "", false
+}
+
+// Built-in keyword classifiers
+var (
+	// Terminations reported by k8s.ContainerTerminationInfo are always errors,
+	// whatever the reason
+	terminationClassifier = RegexClassifier{Category: CategoryError, Pattern: regexp.MustCompile(`^\[hallucino\] container \S+ terminated: `)}
+	// Warning events reported by k8s.ListPodEvents explain failures the logs
+	// can't, such as scheduling or image pull errors
+	eventClassifier   = RegexClassifier{Category: CategoryError, Pattern: regexp.MustCompile(`^\[hallucino\] event Warning `)}
+	errorClassifier   = RegexClassifier{Category: CategoryError, Pattern: regexp.MustCompile(`(?i)error|critical|fatal|panic`)}
+	warningClassifier = RegexClassifier{Category: CategoryWarning, Pattern: regexp.MustCompile(`(?i)warning|warn`)}
+	restartClassifier = RegexClassifier{Category: CategoryRestart, Pattern: regexp.MustCompile(`(?i)pod|container.*restart`)}
+)
+
+// DefaultClassifiers are the built-in classifiers, in order of precedence.
+// Structured levels are read first, falling back to keywords for lines
+// without one.
+var DefaultClassifiers = []LineClassifier{
+	terminationClassifier,
+	eventClassifier,
+	DefaultSeverityMapping,
+	errorClassifier,
+	warningClassifier,
+	DefaultPerformanceClassifier,
+	restartClassifier,
+}
+
+// Classify returns the category of a log line's content using the built-in
+// classifiers
+func Classify(content string) Category {
+	return classify(k8s.LogEntry{LogContent: content}, DefaultClassifiers)
+}
+
+// classify returns the category from the first classifier that matches
+func classify(log k8s.LogEntry, classifiers []LineClassifier) Category {
+	category, _ := classifyRule(log, classifiers)
+	return category
+}
+
+// classifyRule is classify, also returning the position of the classifier
+// that matched, or -1 when none did
+func classifyRule(log k8s.LogEntry, classifiers []LineClassifier) (Category, int) {
+	for i, classifier := range classifiers {
+		if category, matched := classifier.Classify(log); matched {
+			return Category(category), i
+		}
+	}
+	return CategoryNone, -1
+}
+
 // analyzeLine performs detailed analysis of each log line
-func (la *LogAnalyzer) analyzeLine(log k8s.LogEntry) {
-	errorRegex := regexp.MustCompile(`(?i)error|critical|fatal|panic`)
-	warningRegex := regexp.MustCompile(`(?i)warning|warn`)
-	performanceRegex := regexp.MustCompile(`(?i)timeout|latency|slow|high load`)
-	restartRegex := regexp.MustCompile(`(?i)pod|container.*restart`)
-
-	switch {
-	case errorRegex.MatchString(log.LogContent):
+func (la *LogAnalyzer) analyzeLine(index int, log k8s.LogEntry) {
+	pod := podKey{namespace: log.Namespace, pod: log.PodName}
+	podCounts := la.podStats[pod]
+	containerKey := log.Namespace + "/" + log.PodName + "/" + log.Container
+	containerCounts := la.containerStats[containerKey]
+
+	category, rule := classifyRule(log, la.classifiers)
+	la.decisions = append(la.decisions, decision{category: category, rule: rule})
+
+	// Markers are classified as usual too, an error may have been cut short
+	if k8s.IsTruncation(log.LogContent) {
+		la.truncations = append(la.truncations, log)
+	}
+
+	switch category {
+	case CategoryError:
 		la.errorCount++
+		podCounts.Errors++
+		containerCounts.Errors++
 		la.criticalEvents = append(la.criticalEvents, log)
-	case warningRegex.MatchString(log.LogContent):
+		la.criticalIndexes = append(la.criticalIndexes, index)
+	case CategoryWarning:
 		la.warningCount++
-	case performanceRegex.MatchString(log.LogContent):
+		podCounts.Warnings++
+		containerCounts.Warnings++
+	case CategoryPerformance:
+		podCounts.PerformanceIssues++
+		containerCounts.PerformanceIssues++
 		la.performanceIssues = append(la.performanceIssues, log)
-	case restartRegex.MatchString(log.LogContent):
+	case CategoryRestart:
 		log.LogContent = "Restart Event: " + log.LogContent
 		la.criticalEvents = append(la.criticalEvents, log)
+		la.criticalIndexes = append(la.criticalIndexes, index)
+	case CategoryNone:
+	default:
+		// Categories introduced by custom classifiers
+		la.customFindings[category] = append(la.customFindings[category], log)
+	}
+
+	la.podStats[pod] = podCounts
+	la.containerStats[containerKey] = containerCounts
+}
+
+// containerLabel names the container for reports, marking init containers so
+// startup failures stand out
+func containerLabel(log k8s.LogEntry) string {
+	if log.InitContainer {
+		return log.Container + " (init)"
+	}
+	return log.Container
+}
+
+// ErrorCount returns the number of lines classified as errors
+func (la *LogAnalyzer) ErrorCount() int {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return la.errorCount
+}
+
+// WarningCount returns the number of lines classified as warnings
+func (la *LogAnalyzer) WarningCount() int {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return la.warningCount
+}
+
+// CriticalCount returns the number of critical events, including restarts
+func (la *LogAnalyzer) CriticalCount() int {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return len(la.criticalEvents)
+}
+
+// CriticalEvents returns the entries classified as errors or restarts
+func (la *LogAnalyzer) CriticalEvents() []k8s.LogEntry {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return la.criticalEvents[:len(la.criticalEvents):len(la.criticalEvents)]
+}
+
+// HasFindings reports whether any critical events, performance issues or custom
+// findings were detected
+func (la *LogAnalyzer) HasFindings() bool {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return len(la.criticalEvents) > 0 || len(la.performanceIssues) > 0 || len(la.customFindings) > 0
+}
+
+// CustomFindings returns the entries assigned categories other than the
+// built-in ones, keyed by category
+func (la *LogAnalyzer) CustomFindings() map[Category][]k8s.LogEntry {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+
+	findings := make(map[Category][]k8s.LogEntry, len(la.customFindings))
+	for category, logs := range la.customFindings {
+		findings[category] = logs[:len(logs):len(logs)]
 	}
+	return findings
+}
+
+// Truncations returns the entries marking missing log data, such as byte
+// limits reached during retrieval or rotation and truncation markers, which
+// mean the capture is incomplete
+func (la *LogAnalyzer) Truncations() []k8s.LogEntry {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return la.truncations[:len(la.truncations):len(la.truncations)]
+}
+
+// SetTimestampFormat sets the preset or Go layout used for timestamps in
+// DetailedReport, see k8s.FormatTimestamp
+func (la *LogAnalyzer) SetTimestampFormat(format string) {
+	la.mu.Lock()
+	defer la.mu.Unlock()
+	la.timestampFormat = format
+}
+
+// DetailedReport returns the local Markdown analysis report
+func (la *LogAnalyzer) DetailedReport() string {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return la.generateDetailedReport(la.timestampFormat)
 }
 
 // generateDetailedReport creates a comprehensive log analysis report
-func (la *LogAnalyzer) generateDetailedReport() string {
-	report := "### Kubernetes Log Analysis Report\n\n"
-	report += fmt.Sprintf("- **Total Log Entries:** %d\n", len(la.logs))
-	report += fmt.Sprintf("- **Error Count:** %d\n", la.errorCount)
-	report += fmt.Sprintf("- **Warning Count:** %d\n\n", la.warningCount)
+func (la *LogAnalyzer) generateDetailedReport(timestampFormat string) string {
+	var report strings.Builder
+	report.Grow(la.reportSize())
+	report.WriteString(la.reportSummary())
 
-	report += "#### Critical Events\n"
+	// Say up front when the findings may not be the whole story
+	if len(la.truncations) > 0 {
+		report.WriteString("#### Incomplete Capture\n")
+		report.WriteString("Log data is missing around these entries, so events before or after them may not be shown:\n")
+		for _, log := range la.truncations {
+			la.writeReportLine(&report, log, timestampFormat)
+		}
+		report.WriteString("\n")
+	}
+
+	report.WriteString("#### Critical Events\n")
 	if len(la.criticalEvents) > 0 {
 		for _, event := range la.criticalEvents {
-			report += fmt.Sprintf("- `%s | %s | %s`: %s\n",
-				event.Timestamp,
-				event.PodName,
-				event.Container,
-				event.LogContent,
-			)
+			la.writeReportLine(&report, event, timestampFormat)
 		}
 	} else {
-		report += "- No critical events detected.\n"
+		report.WriteString("- No critical events detected.\n")
 	}
 
-	report += "\n#### Performance Issues\n"
+	report.WriteString("\n#### Performance Issues\n")
 	if len(la.performanceIssues) > 0 {
 		for _, issue := range la.performanceIssues {
-			report += fmt.Sprintf("- `%s | %s | %s`: %s\n",
-				issue.Timestamp,
-				issue.PodName,
-				issue.Container,
-				issue.LogContent,
-			)
+			la.writeReportLine(&report, issue, timestampFormat)
 		}
 	} else {
-		report += "- No significant performance issues detected.\n"
+		report.WriteString("- No significant performance issues detected.\n")
 	}
 
-	return report
+	// Findings from custom classifiers, in a stable order
+	categories := make([]string, 0, len(la.customFindings))
+	for category := range la.customFindings {
+		categories = append(categories, string(category))
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		fmt.Fprintf(&report, "\n#### Custom Findings: %s\n", category)
+		for _, log := range la.customFindings[Category(category)] {
+			la.writeReportLine(&report, log, timestampFormat)
+		}
+	}
+
+	return report.String()
+}
+
+// reportLineOverhead approximates the bytes a report line adds to the entry's
+// content: the timestamp, pod, container and Markdown punctuation
+const reportLineOverhead = 96
+
+// reportSize estimates the length of the detailed report, so it can be built
+// without repeatedly growing the buffer
+func (la *LogAnalyzer) reportSize() int {
+	size := 512
+	add := func(logs []k8s.LogEntry) {
+		for _, log := range logs {
+			size += len(log.LogContent) + reportLineOverhead
+		}
+	}
+	add(la.truncations)
+	add(la.criticalEvents)
+	add(la.performanceIssues)
+	for _, logs := range la.customFindings {
+		add(logs)
+	}
+	return size
+}
+
+// writeReportLine writes an entry as a report list item
+func (la *LogAnalyzer) writeReportLine(report *strings.Builder, log k8s.LogEntry, timestampFormat string) {
+	fmt.Fprintf(report, "- `%s%s | %s`: %s\n",
+		reportTimestamp(log.Timestamp, timestampFormat),
+		log.PodName,
+		containerLabel(log),
+		la.content(log),
+	)
+}
+
+// reportSummary returns the report heading and overall counts
+func (la *LogAnalyzer) reportSummary() string {
+	summary := "### Kubernetes Log Analysis Report\n\n"
+	summary += fmt.Sprintf("- **Total Log Entries:** %d\n", len(la.logs))
+	summary += fmt.Sprintf("- **Error Count:** %d\n", la.errorCount)
+	summary += fmt.Sprintf("- **Warning Count:** %d\n\n", la.warningCount)
+	return summary
+}
+
+// reportTimestamp formats a timestamp as a report column prefix, which is
+// omitted entirely when timestamps are disabled
+func reportTimestamp(timestamp, format string) string {
+	if format == k8s.TimestampNone {
+		return ""
+	}
+	return k8s.FormatTimestamp(timestamp, format) + " | "
+}
+
+// StatsByPod returns finding counts keyed by "namespace/pod"
+func (la *LogAnalyzer) StatsByPod() map[string]Counts {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return la.statsByPod()
+}
+
+func (la *LogAnalyzer) statsByPod() map[string]Counts {
+	stats := make(map[string]Counts, len(la.podStats))
+	for pod, counts := range la.podStats {
+		stats[pod.namespace+"/"+pod.pod] = counts
+	}
+	return stats
+}
+
+// StatsByContainer returns finding counts keyed by "namespace/pod/container"
+func (la *LogAnalyzer) StatsByContainer() map[string]Counts {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+
+	stats := make(map[string]Counts, len(la.containerStats))
+	for container, counts := range la.containerStats {
+		stats[container] = counts
+	}
+	return stats
+}
+
+// WriteStats writes the report totals followed by per-pod and per-container
+// breakdowns as aligned plain-text tables
+func (la *LogAnalyzer) WriteStats(w io.Writer) error {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Total Log Entries:\t%d\n", len(la.logs))
+	fmt.Fprintf(tw, "Error Count:\t%d\n", la.errorCount)
+	fmt.Fprintf(tw, "Warning Count:\t%d\n", la.warningCount)
+	fmt.Fprintf(tw, "Critical Events:\t%d\n", len(la.criticalEvents))
+	fmt.Fprintf(tw, "Performance Issues:\t%d\n", len(la.performanceIssues))
+
+	writeCounts(tw, "NAMESPACE/POD", la.statsByPod())
+	writeCounts(tw, "NAMESPACE/POD/CONTAINER", la.containerStats)
+
+	return tw.Flush()
+}
+
+// PodStat is a pod's finding counts, as ranked by TopPods
+type PodStat struct {
+	Namespace string
+	Pod       string
+	Counts
+}
+
+// Noise is the number of errors and warnings the pod logged
+func (p PodStat) Noise() int {
+	return p.Errors + p.Warnings
+}
+
+// TopPods returns the n pods with the most errors and warnings, noisiest first.
+// Pods tied with the last place are included too, so more than n pods may be
+// returned. Ties are ordered by errors, then by namespace and pod name. Pods
+// without errors or warnings are never returned, and n <= 0 returns all pods.
+func (la *LogAnalyzer) TopPods(n int) []PodStat {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return la.topPods(n)
+}
+
+func (la *LogAnalyzer) topPods(n int) []PodStat {
+	var stats []PodStat
+	for pod, counts := range la.podStats {
+		if counts.Errors+counts.Warnings == 0 {
+			continue
+		}
+		stats = append(stats, PodStat{Namespace: pod.namespace, Pod: pod.pod, Counts: counts})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.Noise() != b.Noise() {
+			return a.Noise() > b.Noise()
+		}
+		if a.Errors != b.Errors {
+			return a.Errors > b.Errors
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Pod < b.Pod
+	})
+
+	if n <= 0 || len(stats) <= n {
+		return stats
+	}
+
+	// Extend the cut-off past pods sharing the last place
+	end := n
+	for end < len(stats) && stats[end].Noise() == stats[n-1].Noise() {
+		end++
+	}
+	return stats[:end]
+}
+
+// WriteTopPods writes the n noisiest pods as an aligned table, giving tied
+// pods the same rank
+func (la *LogAnalyzer) WriteTopPods(w io.Writer, n int) error {
+	top := la.TopPods(n)
+	if len(top) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tNAMESPACE\tPOD\tERRORS\tWARNINGS")
+	rank := 0
+	for i, stat := range top {
+		if i == 0 || stat.Noise() != top[i-1].Noise() {
+			rank = i + 1
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\n", rank, stat.Namespace, stat.Pod, stat.Errors, stat.Warnings)
+	}
+	return tw.Flush()
+}
+
+// writeCounts writes a table of counts sorted by key
+func writeCounts(w io.Writer, heading string, stats map[string]Counts) {
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "\n%s\tERRORS\tWARNINGS\n", heading)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", key, stats[key].Errors, stats[key].Warnings)
+	}
 }
@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"fmt"
+	"hallucino/internal/k8s"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestDetailedReport(t *testing.T) {
+	logs := []k8s.LogEntry{
+		{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:00Z", LogContent: "ERROR: connection refused"},
+		{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:01Z", LogContent: "request served"},
+		{Namespace: "prod", PodName: "api-1", Container: "migrate", InitContainer: true, Timestamp: "2024-11-27T10:00:02Z", LogContent: "fatal: schema locked"},
+		{Namespace: "prod", PodName: "api-2", Container: "api", Timestamp: "2024-11-27T10:00:03Z", LogContent: "WARN: retrying"},
+		{Namespace: "prod", PodName: "api-2", Container: "api", Timestamp: "2024-11-27T10:00:04Z", LogContent: "upstream timeout"},
+		{Namespace: "prod", PodName: "api-2", Container: "api", Timestamp: "2024-11-27T10:00:05Z", LogContent: "audit: user 42 signed in"},
+	}
+	audit := RegexClassifier{Category: "audit", Pattern: regexp.MustCompile(`^audit:`)}
+
+	// The format the report had before it was built with a strings.Builder
+	want := "### Kubernetes Log Analysis Report\n\n" +
+		"- **Total Log Entries:** 6\n" +
+		"- **Error Count:** 2\n" +
+		"- **Warning Count:** 1\n\n" +
+		"#### Critical Events\n" +
+		"- `2024-11-27T10:00:00Z | api-1 | api`: ERROR: connection refused\n" +
+		"- `2024-11-27T10:00:02Z | api-1 | migrate (init)`: fatal: schema locked\n" +
+		"\n#### Performance Issues\n" +
+		"- `2024-11-27T10:00:04Z | api-2 | api`: upstream timeout\n" +
+		"\n#### Custom Findings: audit\n" +
+		"- `2024-11-27T10:00:05Z | api-2 | api`: audit: user 42 signed in\n"
+
+	if got := NewLogAnalyzer(logs, audit).DetailedReport(); got != want {
+		t.Errorf("DetailedReport() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestDetailedReportWithoutFindings(t *testing.T) {
+	logs := []k8s.LogEntry{
+		{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:00Z", LogContent: "request served"},
+	}
+
+	want := "### Kubernetes Log Analysis Report\n\n" +
+		"- **Total Log Entries:** 1\n" +
+		"- **Error Count:** 0\n" +
+		"- **Warning Count:** 0\n\n" +
+		"#### Critical Events\n" +
+		"- No critical events detected.\n" +
+		"\n#### Performance Issues\n" +
+		"- No significant performance issues detected.\n"
+
+	if got := NewLogAnalyzer(logs).DetailedReport(); got != want {
+		t.Errorf("DetailedReport() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func BenchmarkGenerateDetailedReport(b *testing.B) {
+	logs := make([]k8s.LogEntry, 30000)
+	for i := range logs {
+		logs[i] = k8s.LogEntry{
+			Namespace:  "prod",
+			PodName:    fmt.Sprintf("api-%d", i%50),
+			Container:  "api",
+			Timestamp:  "2024-11-27T10:00:00Z",
+			LogContent: fmt.Sprintf("ERROR: request %d failed: connection refused by upstream", i),
+		}
+	}
+	la := NewLogAnalyzer(logs)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = la.DetailedReport()
+	}
+}
+
+func TestStatsSeparateNamespaces(t *testing.T) {
+	logs := []k8s.LogEntry{
+		{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:00Z", LogContent: "ERROR: connection refused"},
+		{Namespace: "staging", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:01Z", LogContent: "WARN: retrying"},
+	}
+	la := NewLogAnalyzer(logs)
+
+	wantPods := map[string]Counts{
+		"prod/api-1":    {Errors: 1},
+		"staging/api-1": {Warnings: 1},
+	}
+	if got := la.StatsByPod(); !reflect.DeepEqual(got, wantPods) {
+		t.Errorf("StatsByPod() = %+v, want %+v", got, wantPods)
+	}
+	wantContainers := map[string]Counts{
+		"prod/api-1/api":    {Errors: 1},
+		"staging/api-1/api": {Warnings: 1},
+	}
+	if got := la.StatsByContainer(); !reflect.DeepEqual(got, wantContainers) {
+		t.Errorf("StatsByContainer() = %+v, want %+v", got, wantContainers)
+	}
+}
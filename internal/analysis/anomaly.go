@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultAnomalyWindow is the bucket size used for the AI prompt
+	DefaultAnomalyWindow = time.Minute
+
+	// rateSpikeFactor is how many times the median rate a window must exceed
+	rateSpikeFactor = 5
+
+	// minSpikeEntries stops small absolute counts from being reported as spikes
+	minSpikeEntries = 10
+
+	// minBaselineWindows is the number of windows needed for a meaningful median
+	minBaselineWindows = 3
+)
+
+// Anomaly is a time window in which a pod logged far more than its usual rate
+type Anomaly struct {
+	Namespace string
+	Pod       string
+	Start     time.Time
+	Window    time.Duration
+	Count     int
+	// Median is the pod's median number of entries per window
+	Median float64
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s | %s | %s | %d entries in %s (median %.1f)",
+		a.Start.Format(time.RFC3339), a.Namespace, a.Pod, a.Count, a.Window, a.Median)
+}
+
+// DetectRateAnomalies buckets each pod's entries into windows of the given size
+// and flags windows whose count exceeds rateSpikeFactor times the pod's median.
+// Quiet windows between a pod's first and last entry count towards the median,
+// and entries with unparseable timestamps are ignored.
+func (la *LogAnalyzer) DetectRateAnomalies(window time.Duration) []Anomaly {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return la.detectRateAnomalies(window)
+}
+
+func (la *LogAnalyzer) detectRateAnomalies(window time.Duration) []Anomaly {
+	if window <= 0 {
+		return nil
+	}
+
+	buckets := map[podKey]map[time.Time]int{}
+	for _, log := range la.logs {
+		t, err := time.Parse(time.RFC3339Nano, log.Timestamp)
+		if err != nil {
+			continue
+		}
+		pod := podKey{namespace: log.Namespace, pod: log.PodName}
+		if buckets[pod] == nil {
+			buckets[pod] = map[time.Time]int{}
+		}
+		buckets[pod][t.Truncate(window)]++
+	}
+
+	var anomalies []Anomaly
+	for pod, counts := range buckets {
+		// Span every window from the first to the last entry
+		var first, last time.Time
+		for start := range counts {
+			if first.IsZero() || start.Before(first) {
+				first = start
+			}
+			if start.After(last) {
+				last = start
+			}
+		}
+		windows := int(last.Sub(first)/window) + 1
+		if windows < minBaselineWindows {
+			continue
+		}
+
+		series := make([]int, windows)
+		for start, count := range counts {
+			series[int(start.Sub(first)/window)] = count
+		}
+		median := medianOf(series)
+
+		for i, count := range series {
+			if count >= minSpikeEntries && float64(count) > rateSpikeFactor*median {
+				anomalies = append(anomalies, Anomaly{
+					Namespace: pod.namespace,
+					Pod:       pod.pod,
+					Start:     first.Add(time.Duration(i) * window),
+					Window:    window,
+					Count:     count,
+					Median:    median,
+				})
+			}
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		return a.Start.Before(b.Start)
+	})
+
+	return anomalies
+}
+
+// medianOf returns the median of the values without modifying them
+func medianOf(values []int) float64 {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return float64(sorted[mid-1]+sorted[mid]) / 2
+	}
+	return float64(sorted[mid])
+}
@@ -0,0 +1,61 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// insightsCache stores generated insights on disk, keyed by a hash of the
+// prompt and the deployment that answered it
+type insightsCache struct {
+	dir string
+}
+
+// cacheDir returns the directory used for cached insights under the user cache dir
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "hallucino"), nil
+}
+
+// ClearCache removes all cached insights
+func ClearCache() error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// cacheKey hashes everything that determines the completion
+func cacheKey(deployment string, prompts ...string) string {
+	h := sha256.New()
+	h.Write([]byte(deployment))
+	for _, prompt := range prompts {
+		h.Write([]byte{0})
+		h.Write([]byte(prompt))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the cached insights for key, if present
+func (c *insightsCache) get(key string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".md"))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// put stores insights under key
+func (c *insightsCache) put(key, insights string) error {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.dir, key+".md"), []byte(insights), 0o600)
+}
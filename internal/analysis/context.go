@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"fmt"
+	"hallucino/internal/k8s"
+	"strings"
+)
+
+// promptLine formats an entry for the AI prompt, leading with its capture
+// when several were merged
+func (la *LogAnalyzer) promptLine(log k8s.LogEntry) string {
+	line := fmt.Sprintf("%s | %s | %s | %s",
+		log.Timestamp, log.Namespace, log.PodName, la.content(log),
+	)
+	if log.Source != "" {
+		line = log.Source + " | " + line
+	}
+	return line
+}
+
+// incompleteNote tells the AI which entries mark missing log data, or returns
+// an empty string when the capture is complete
+func (la *LogAnalyzer) incompleteNote() string {
+	if len(la.truncations) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(la.truncations))
+	for _, log := range la.truncations {
+		lines = append(lines, la.promptLine(log))
+	}
+	return fmt.Sprintf("Incomplete Capture: log data is missing around these entries, so don't assume nothing happened there:\n%s\n\n",
+		strings.Join(lines, "\n"))
+}
+
+// criticalEventTexts formats the critical events for the AI prompt. With
+// contextLines > 0 each event is shown with up to that many entries before and
+// after it from the same container, like grep -C: events are prefixed with
+// "> ", overlapping windows are merged and separate windows split by "--".
+func (la *LogAnalyzer) criticalEventTexts(contextLines int) []string {
+	var texts []string
+	if contextLines <= 0 {
+		for _, event := range la.criticalEvents {
+			texts = append(texts, la.promptLine(event))
+		}
+		return texts
+	}
+
+	// Index each container's entries in log order
+	streamOf := func(log k8s.LogEntry) string {
+		return log.Namespace + "/" + log.PodName + "/" + log.Container
+	}
+	streams := map[string][]int{}
+	position := make([]int, len(la.logs))
+	for i, log := range la.logs {
+		key := streamOf(log)
+		position[i] = len(streams[key])
+		streams[key] = append(streams[key], i)
+	}
+
+	// Group events by container so windows within a container can be merged,
+	// keeping their classified content such as the restart prefix
+	events := map[int]k8s.LogEntry{}
+	var order []string
+	byStream := map[string][]int{}
+	for j, idx := range la.criticalIndexes {
+		events[idx] = la.criticalEvents[j]
+		key := streamOf(la.logs[idx])
+		if _, ok := byStream[key]; !ok {
+			order = append(order, key)
+		}
+		byStream[key] = append(byStream[key], idx)
+	}
+
+	for _, key := range order {
+		stream := streams[key]
+		printedTo := -1
+		for _, idx := range byStream[key] {
+			start := max(0, position[idx]-contextLines)
+			end := min(len(stream)-1, position[idx]+contextLines)
+			if start <= printedTo {
+				// Continue the previous window instead of repeating entries
+				start = printedTo + 1
+			} else if len(texts) > 0 {
+				texts = append(texts, "--")
+			}
+
+			for p := start; p <= end; p++ {
+				if event, ok := events[stream[p]]; ok {
+					texts = append(texts, "> "+la.promptLine(event))
+				} else {
+					texts = append(texts, "  "+la.promptLine(la.logs[stream[p]]))
+				}
+			}
+			printedTo = max(printedTo, end)
+		}
+	}
+
+	return texts
+}
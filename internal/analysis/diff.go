@@ -0,0 +1,51 @@
+package analysis
+
+import (
+	"hallucino/internal/k8s"
+	"regexp"
+	"strings"
+)
+
+// Patterns for the parts of a line that differ between otherwise identical
+// events, applied in order
+var (
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?|\b\d{2}:\d{2}:\d{2}(?:\.\d+)?\b`)
+	uuidPattern      = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	ipPattern        = regexp.MustCompile(`(?i)\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b|\b(?:[0-9a-f]{1,4}:){2,7}[0-9a-f]{1,4}\b`)
+	hexIDPattern     = regexp.MustCompile(`(?i)\b(?:0x)?[0-9a-f]{8,}\b`)
+	numberPattern    = regexp.MustCompile(`\d+`)
+)
+
+// NormalizeContent strips timestamps and replaces UUIDs, IP addresses, hex IDs
+// and numbers with placeholders, so repeats of the same event compare equal
+func NormalizeContent(content string) string {
+	content = timestampPattern.ReplaceAllString(content, "")
+	content = uuidPattern.ReplaceAllString(content, "<id>")
+	content = ipPattern.ReplaceAllString(content, "<ip>")
+	content = hexIDPattern.ReplaceAllStringFunc(content, func(match string) string {
+		// Leave words that happen to be spelt in hex, like "deadbeef"
+		if !strings.ContainsAny(match, "0123456789") {
+			return match
+		}
+		return "<id>"
+	})
+	content = numberPattern.ReplaceAllString(content, "<n>")
+	return strings.Join(strings.Fields(content), " ")
+}
+
+// NewCriticalEvents returns the critical events in current whose normalized
+// content doesn't appear among the critical events in baseline
+func NewCriticalEvents(baseline, current *LogAnalyzer) []k8s.LogEntry {
+	seen := map[string]bool{}
+	for _, log := range baseline.CriticalEvents() {
+		seen[NormalizeContent(log.LogContent)] = true
+	}
+
+	var events []k8s.LogEntry
+	for _, log := range current.CriticalEvents() {
+		if !seen[NormalizeContent(log.LogContent)] {
+			events = append(events, log)
+		}
+	}
+	return events
+}
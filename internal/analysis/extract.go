@@ -0,0 +1,111 @@
+package analysis
+
+import (
+	"encoding/json"
+	"hallucino/internal/k8s"
+	"regexp"
+	"strings"
+)
+
+// ExtractFields pulls the named fields out of JSON log content, formatted as
+// "name=value" pairs in the order requested. Dotted names such as "http.status"
+// reach into nested objects. It returns nil when the content isn't a JSON
+// object or none of the fields are present.
+func ExtractFields(content string, fields []string) []string {
+	content = strings.TrimSpace(content)
+	if len(fields) == 0 || !strings.HasPrefix(content, "{") {
+		return nil
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal([]byte(content), &object); err != nil {
+		return nil
+	}
+
+	var pairs []string
+	for _, field := range fields {
+		if value, ok := lookupField(object, field); ok {
+			pairs = append(pairs, field+"="+formatValue(value))
+		}
+	}
+	return pairs
+}
+
+// lookupField resolves a dotted field name, preferring an exact key match so
+// keys that themselves contain dots still work
+func lookupField(object map[string]any, field string) (any, bool) {
+	if value, ok := object[field]; ok {
+		return value, true
+	}
+
+	head, rest, found := strings.Cut(field, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := object[head].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookupField(nested, rest)
+}
+
+// formatValue renders strings as-is and other JSON values compactly
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// FieldExtractor reads one field from log content: by name from JSON entries,
+// as ExtractFields does, and from key=value or key: value pairs in other
+// entries, e.g. trace_id=4bf92f35 or "trace_id": "4bf92f35"
+type FieldExtractor struct {
+	field   string
+	pattern *regexp.Regexp
+}
+
+// NewFieldExtractor creates an extractor for the named field
+func NewFieldExtractor(field string) *FieldExtractor {
+	return &FieldExtractor{
+		field:   field,
+		pattern: regexp.MustCompile(`(?:^|[^\w.])"?` + regexp.QuoteMeta(field) + `"?\s*[=:]\s*"?([^\s",;}\])]+)`),
+	}
+}
+
+// Value returns the field's value in the content, if present
+func (fe *FieldExtractor) Value(content string) (string, bool) {
+	if trimmed := strings.TrimSpace(content); strings.HasPrefix(trimmed, "{") {
+		var object map[string]any
+		if json.Unmarshal([]byte(trimmed), &object) == nil {
+			if value, ok := lookupField(object, fe.field); ok {
+				return formatValue(value), true
+			}
+		}
+	}
+	if match := fe.pattern.FindStringSubmatch(content); match != nil {
+		return match[1], true
+	}
+	return "", false
+}
+
+// SetExtractFields sets the JSON fields shown instead of the whole entry in
+// DetailedReport and the AI prompt, see ExtractFields
+func (la *LogAnalyzer) SetExtractFields(fields []string) {
+	la.mu.Lock()
+	defer la.mu.Unlock()
+	la.extractFields = fields
+}
+
+// content returns the entry's content as shown in reports: the extracted fields
+// for JSON entries that have them, otherwise the content unchanged
+func (la *LogAnalyzer) content(log k8s.LogEntry) string {
+	if pairs := ExtractFields(log.LogContent, la.extractFields); len(pairs) > 0 {
+		return strings.Join(pairs, " ")
+	}
+	return log.LogContent
+}
@@ -0,0 +1,130 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+	"hallucino/internal/k8s"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/yuin/goldmark"
+)
+
+// htmlReport is the self-contained page written by WriteHTMLReport. Styles are
+// inlined so the file can be attached to a ticket or wiki as-is.
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Kubernetes Log Analysis Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 1100px; color: #24292f; line-height: 1.5; }
+h1, h2 { border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; font-size: 14px; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+td.content { font-family: ui-monospace, Menlo, Consolas, monospace; white-space: pre-wrap; word-break: break-word; }
+tr.critical { background: #ffd7d5; }
+tr.error { background: #ffebe9; }
+tr.restart { background: #fff1e5; }
+tr.performance { background: #fff8c5; }
+ul.summary { list-style: none; padding: 0; }
+pre, code { background: #f6f8fa; border-radius: 4px; }
+pre { padding: 1em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Kubernetes Log Analysis Report</h1>
+<ul class="summary">
+<li><strong>Total Log Entries:</strong> {{.Total}}</li>
+<li><strong>Error Count:</strong> {{.Errors}}</li>
+<li><strong>Warning Count:</strong> {{.Warnings}}</li>
+</ul>
+{{if .Insights}}<h2>AI Insights</h2>
+{{.Insights}}
+{{end}}<h2>Critical Events</h2>
+{{template "rows" .CriticalEvents}}
+<h2>Performance Issues</h2>
+{{template "rows" .PerformanceIssues}}
+</body>
+</html>
+{{define "rows"}}{{if .}}<table>
+<tr><th>Timestamp</th><th>Pod</th><th>Container</th><th>Entry</th></tr>
+{{range .}}<tr class="{{.Class}}"><td>{{.Timestamp}}</td><td>{{.Pod}}</td><td>{{.Container}}</td><td class="content">{{.Content}}</td></tr>
+{{end}}</table>{{else}}<p>None detected.</p>{{end}}{{end}}
+`))
+
+// htmlRow is an entry in one of the report tables, with Class naming the
+// severity that colors the row
+type htmlRow struct {
+	Class     string
+	Timestamp string
+	Pod       string
+	Container string
+	Content   string
+}
+
+// WriteHTMLReport writes the detailed report as a standalone HTML page, with
+// insights, when not empty, rendered from Markdown above the event tables
+func WriteHTMLReport(w io.Writer, la *LogAnalyzer, insights string) error {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+
+	data := struct {
+		Total, Errors, Warnings int
+		Insights                template.HTML
+		CriticalEvents          []htmlRow
+		PerformanceIssues       []htmlRow
+	}{
+		Total:    len(la.logs),
+		Errors:   la.errorCount,
+		Warnings: la.warningCount,
+	}
+
+	if insights != "" {
+		var rendered bytes.Buffer
+		if err := goldmark.Convert([]byte(insights), &rendered); err != nil {
+			return fmt.Errorf("failed to render insights: %w", err)
+		}
+		// goldmark escapes raw HTML in the Markdown by default
+		data.Insights = template.HTML(rendered.String())
+	}
+
+	for _, event := range la.criticalEvents {
+		data.CriticalEvents = append(data.CriticalEvents, la.htmlRow(event, eventClass(event)))
+	}
+	for _, issue := range la.performanceIssues {
+		data.PerformanceIssues = append(data.PerformanceIssues, la.htmlRow(issue, string(CategoryPerformance)))
+	}
+
+	return htmlReport.Execute(w, data)
+}
+
+// htmlRow converts an entry to a report table row
+func (la *LogAnalyzer) htmlRow(log k8s.LogEntry, class string) htmlRow {
+	timestamp := ""
+	if la.timestampFormat != k8s.TimestampNone {
+		timestamp = k8s.FormatTimestamp(log.Timestamp, la.timestampFormat)
+	}
+	return htmlRow{
+		Class:     class,
+		Timestamp: timestamp,
+		Pod:       log.PodName,
+		Container: containerLabel(log),
+		Content:   la.content(log),
+	}
+}
+
+// eventClass returns the row class of a critical event: restart events, which
+// analyzeLine prefixes, critical or fatal levels, and other errors
+func eventClass(log k8s.LogEntry) string {
+	switch {
+	case strings.HasPrefix(log.LogContent, "Restart Event: "):
+		return string(CategoryRestart)
+	case DefaultSeverityMapping.Severity(log.LogContent) == SeverityCritical:
+		return SeverityCritical.String()
+	default:
+		return string(CategoryError)
+	}
+}
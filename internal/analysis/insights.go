@@ -0,0 +1,70 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
+)
+
+// structuredPrompt is appended to the system prompt to have the model answer
+// with JSON matching Insights rather than prose
+const structuredPrompt = `
+
+**Response Format:**
+Respond only with a JSON object of this shape, without Markdown fences:
+{
+  "summary": "A few sentences summarizing the key events.",
+  "issues": [
+    {"severity": "critical, error or warning", "description": "The issue and its likely cause.", "pods": ["affected pod names"]}
+  ],
+  "recommendations": ["A specific step to address the issues."]
+}`
+
+// Insights is the machine-readable form of the AI analysis
+type Insights struct {
+	Summary         string   `json:"summary"`
+	Issues          []Issue  `json:"issues"`
+	Recommendations []string `json:"recommendations"`
+}
+
+// Issue is a problem detected in the logs
+type Issue struct {
+	Severity    string   `json:"severity"`
+	Description string   `json:"description"`
+	Pods        []string `json:"pods,omitempty"`
+}
+
+// GenerateStructuredInsights asks the model, in JSON mode, for insights it can
+// parse into Insights for consumption by other tools
+func (oa *OpenAIAnalyzer) GenerateStructuredInsights(ctx context.Context, logAnalyzer *LogAnalyzer) (*Insights, error) {
+	enc, err := encodingFor(oa.config.DeploymentName)
+	if err != nil {
+		return nil, err
+	}
+	systemPrompt := oa.config.SystemPrompt + structuredPrompt
+	userPrompt := oa.config.userPrompt(enc, logAnalyzer, systemPrompt)
+
+	response, err := oa.complete(ctx, enc, systemPrompt, userPrompt, &azopenai.ChatCompletionsJSONResponseFormat{})
+	if err != nil {
+		return nil, err
+	}
+	return parseInsights(response)
+}
+
+// parseInsights decodes the model's JSON response, making the lists empty
+// rather than null so consumers needn't check
+func parseInsights(response string) (*Insights, error) {
+	var insights Insights
+	if err := json.Unmarshal([]byte(response), &insights); err != nil {
+		return nil, fmt.Errorf("failed to parse structured insights: %w", err)
+	}
+	if insights.Issues == nil {
+		insights.Issues = []Issue{}
+	}
+	if insights.Recommendations == nil {
+		insights.Recommendations = []string{}
+	}
+	return &insights, nil
+}
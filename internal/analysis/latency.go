@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"hallucino/internal/k8s"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLatencyThreshold is the duration above which a measured latency is a
+// performance issue
+const DefaultLatencyThreshold = time.Second
+
+// NumericField is a key=number[unit] pair found in a log line
+type NumericField struct {
+	Key   string
+	Value float64
+	Unit  string
+}
+
+// numericFieldPattern matches key=number[unit] pairs in text, logfmt and JSON
+// lines, e.g. latency=4200ms, "duration": 1.5s or took: 300
+var numericFieldPattern = regexp.MustCompile(`"?([A-Za-z_][\w.-]*)"?\s*[=:]\s*"?(\d+(?:\.\d+)?)\s*(ns|us|µs|ms|s|m|h)?\b`)
+
+// ExtractNumericFields returns the key=number[unit] pairs in the content
+func ExtractNumericFields(content string) []NumericField {
+	var fields []NumericField
+	for _, match := range numericFieldPattern.FindAllStringSubmatch(content, -1) {
+		value, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			continue
+		}
+		fields = append(fields, NumericField{Key: match[1], Value: value, Unit: match[3]})
+	}
+	return fields
+}
+
+// latencyKeys are the field names that hold a duration
+var latencyKeys = regexp.MustCompile(`(?i)latency|duration|elapsed|took|response_?time|rtt`)
+
+// Duration interprets the field as a duration when its key names one. Without
+// a unit, a _s/_ms/_us/_ns key suffix is used, otherwise milliseconds.
+func (f NumericField) Duration() (time.Duration, bool) {
+	if !latencyKeys.MatchString(f.Key) {
+		return 0, false
+	}
+
+	unit := f.Unit
+	if unit == "" {
+		unit = "ms"
+		key := strings.ToLower(f.Key)
+		for _, suffix := range []string{"ns", "us", "ms", "s"} {
+			if strings.HasSuffix(key, "_"+suffix) {
+				unit = suffix
+				break
+			}
+		}
+	}
+
+	scale := map[string]time.Duration{
+		"ns": time.Nanosecond, "us": time.Microsecond, "µs": time.Microsecond,
+		"ms": time.Millisecond, "s": time.Second, "m": time.Minute, "h": time.Hour,
+	}[unit]
+	return time.Duration(f.Value * float64(scale)), true
+}
+
+// PerformanceClassifier flags lines matching Pattern as performance issues.
+// Lines carrying a measured latency, such as latency=4200ms, are instead
+// flagged only when it exceeds Threshold, whether or not Pattern matches.
+type PerformanceClassifier struct {
+	Pattern   *regexp.Regexp
+	Threshold time.Duration
+}
+
+// DefaultPerformanceClassifier is the built-in performance classifier. Set its
+// Threshold before creating analyzers to change when latencies are flagged.
+var DefaultPerformanceClassifier = &PerformanceClassifier{
+	Pattern:   regexp.MustCompile(`(?i)timeout|latency|slow|high load`),
+	Threshold: DefaultLatencyThreshold,
+}
+
+// Classify implements LineClassifier
+func (c *PerformanceClassifier) Classify(log k8s.LogEntry) (string, bool) {
+	measured := false
+	for _, field := range ExtractNumericFields(log.LogContent) {
+		if d, ok := field.Duration(); ok {
+			if d > c.Threshold {
+				return string(CategoryPerformance), true
+			}
+			measured = true
+		}
+	}
+
+	// Fall back to keywords only when nothing was measured
+	if !measured && c.Pattern.MatchString(log.LogContent) {
+		return string(CategoryPerformance), true
+	}
+	return "", false
+}
@@ -0,0 +1,112 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
+)
+
+// deploymentsAPIVersion is the newest Azure OpenAI data-plane API version that
+// still lists deployments
+const deploymentsAPIVersion = "2022-12-01"
+
+// ErrDeploymentNotFound is returned when the configured deployment doesn't exist
+var ErrDeploymentNotFound = errors.New("deployment not found")
+
+// Deployment is a model deployment available on the Azure OpenAI resource
+type Deployment struct {
+	ID    string `json:"id"`
+	Model string `json:"model"`
+}
+
+// ListDeployments lists the model deployments on the configured Azure OpenAI
+// resource, sorted by name
+func ListDeployments(ctx context.Context, config Config) ([]Deployment, error) {
+	if config.Kind != "" && config.Kind != KindAzure {
+		return nil, fmt.Errorf("listing deployments is only supported for Azure OpenAI")
+	}
+	if err := config.missingFields(FieldAPIKey, FieldEndpoint); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/openai/deployments?api-version=%s", strings.TrimRight(config.Endpoint, "/"), deploymentsAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	req.Header.Set("api-key", config.APIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list deployments: %s", resp.Status)
+	}
+
+	var body struct {
+		Data []Deployment `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode deployments: %w", err)
+	}
+
+	sort.Slice(body.Data, func(i, j int) bool { return body.Data[i].ID < body.Data[j].ID })
+	return body.Data, nil
+}
+
+// ValidateDeployment checks that the configured deployment exists, returning
+// an error naming the available deployments when it doesn't
+func (oa *OpenAIAnalyzer) ValidateDeployment(ctx context.Context) error {
+	deployments, err := ListDeployments(ctx, oa.config)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(deployments))
+	for _, deployment := range deployments {
+		if deployment.ID == oa.config.DeploymentName {
+			return nil
+		}
+		names = append(names, deployment.ID)
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("%w: %q, the resource has no deployments", ErrDeploymentNotFound, oa.config.DeploymentName)
+	}
+	return fmt.Errorf("%w: %q, available deployments: %s", ErrDeploymentNotFound, oa.config.DeploymentName, strings.Join(names, ", "))
+}
+
+// pingMaxTokens bounds the reply to Ping, which only needs to arrive
+const pingMaxTokens = 5
+
+// Ping sends a minimal chat completion, bypassing the cache, to confirm the
+// credentials and deployment work. It returns the model that responded.
+func (oa *OpenAIAnalyzer) Ping(ctx context.Context) (string, error) {
+	req := azopenai.ChatCompletionsOptions{
+		Messages: []azopenai.ChatRequestMessageClassification{
+			&azopenai.ChatRequestUserMessage{
+				Content: azopenai.NewChatRequestUserMessageContent("Reply with OK."),
+			},
+		},
+		DeploymentName: &oa.config.DeploymentName,
+		MaxTokens:      toInt32Ptr(pingMaxTokens),
+	}
+
+	resp, err := oa.client.GetChatCompletions(ctx, req, nil)
+	if err != nil {
+		return "", oa.completionError(ctx, err)
+	}
+	if resp.Model != nil {
+		return *resp.Model, nil
+	}
+	return oa.config.DeploymentName, nil
+}
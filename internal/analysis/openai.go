@@ -2,12 +2,17 @@ package analysis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/pkoukk/tiktoken-go"
+	"go.uber.org/zap"
 )
 
 // AnalysisPrompt is the constant for guiding log analysis
@@ -25,110 +30,365 @@ You are an expert in analyzing Kubernetes logs. Your goal is to analyze the give
 * **Pattern Observations:** (Summary of any recurring patterns or trends in the logs.)
 * **Actionable Recommendations:** (Specific steps or insights to address the issues identified.)`
 
+// ErrorsPrompt replaces AnalysisPrompt with Config.ErrorsOnly, when only the
+// critical events are sent, to focus the response on their root cause
+const ErrorsPrompt = `**Instructions:**
+You are an expert in troubleshooting Kubernetes workloads. You are given only the critical events from the logs, grouped into templates with their counts and the pods they came from. Determine the most likely root cause of the failures, telling causes apart from the symptoms they produce in other pods, and how to fix it.
+
+**Example Format of Response:**
+* **Root Cause:** (The most likely underlying failure, and any other candidates.)
+* **Evidence:** (The events supporting it and how they relate.)
+* **Fix:** (Specific steps to resolve the root cause.)`
+
+// ErrMissingConfig is returned when the OpenAI configuration is incomplete
+var ErrMissingConfig = errors.New("missing required OpenAI configuration")
+
+// Config fields reported by MissingConfigError
+const (
+	FieldAPIKey         = "APIKey"
+	FieldEndpoint       = "Endpoint"
+	FieldDeploymentName = "DeploymentName"
+)
+
+// MissingConfigError names the required Config fields that are empty. It
+// matches ErrMissingConfig with errors.Is.
+type MissingConfigError struct {
+	Fields []string
+}
+
+func (e *MissingConfigError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrMissingConfig, strings.Join(e.Fields, ", "))
+}
+
+func (e *MissingConfigError) Unwrap() error {
+	return ErrMissingConfig
+}
+
+// missingFields reports the named fields that are empty, or nil when all are set
+func (c Config) missingFields(fields ...string) error {
+	values := map[string]string{
+		FieldAPIKey:         c.APIKey,
+		FieldEndpoint:       c.Endpoint,
+		FieldDeploymentName: c.DeploymentName,
+	}
+	var missing []string
+	for _, field := range fields {
+		if values[field] == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &MissingConfigError{Fields: missing}
+}
+
+// Supported OpenAI services
+const (
+	// KindAzure is an Azure OpenAI resource, addressed by deployment
+	KindAzure = "azure"
+	// KindOpenAI is the OpenAI API, addressed by model
+	KindOpenAI = "openai"
+)
+
+// defaultRequestTimeout bounds each OpenAI request when the context has no
+// deadline of its own
+const defaultRequestTimeout = 30 * time.Second
+
+const (
+	// DefaultOpenAIEndpoint is the OpenAI API base URL
+	DefaultOpenAIEndpoint = "https://api.openai.com/v1"
+	// DefaultOpenAIModel is used with KindOpenAI when no model is configured
+	DefaultOpenAIModel = "gpt-4o-mini"
+)
+
 // Config represents the configuration for OpenAI
 type Config struct {
-	APIKey         string
-	Endpoint       string
+	// Kind selects the service, KindAzure when empty
+	Kind     string
+	APIKey   string
+	Endpoint string
+	// DeploymentName is the Azure deployment or, with KindOpenAI, the model
 	DeploymentName string
+	NoCache        bool
+	Logger         *zap.Logger
+	// SystemPrompt overrides AnalysisPrompt as the system message when set
+	SystemPrompt string
+	// ContextLines is the number of entries included before and after each
+	// critical event
+	ContextLines int
+	// ErrorsOnly sends only the critical events, grouped into templates, with
+	// ErrorsPrompt, leaving out the summary, anomalies and performance issues
+	// to cut token usage for error triage
+	ErrorsOnly bool
+	// TraceField and TraceID note in the prompt that the logs were filtered to
+	// a single request flow, e.g. trace_id and 4bf92f35
+	TraceField string
+	TraceID    string
+	// ContextBudget is the maximum tokens sent per request, system prompt
+	// included. When zero it's derived from the model's context window, less
+	// the tokens reserved for the completion.
+	ContextBudget int
+}
+
+// defaultSystemPrompt is the built-in system prompt for the configuration
+func (c Config) defaultSystemPrompt() string {
+	if c.ErrorsOnly {
+		return ErrorsPrompt
+	}
+	return AnalysisPrompt
+}
+
+// userPrompt builds the user message for the configuration, trimming the logs
+// to what the budget leaves after the system prompt it'll be sent with
+func (c Config) userPrompt(enc *tiktoken.Tiktoken, logAnalyzer *LogAnalyzer, systemPrompt string) string {
+	limit := c.logTokenLimit(enc, systemPrompt)
+
+	var prompt string
+	if c.ErrorsOnly {
+		prompt = buildErrorsPrompt(enc, logAnalyzer, limit)
+	} else {
+		prompt = buildUserPrompt(enc, logAnalyzer, c.ContextLines, limit)
+	}
+
+	if c.TraceID != "" {
+		prompt = fmt.Sprintf("These logs all belong to a single request flow, %s=%s, as it passed through the pods below. "+
+			"Follow the request from service to service and identify where and why it failed or slowed down.\n\n%s",
+			c.TraceField, c.TraceID, prompt)
+	}
+	return prompt
 }
 
 // OpenAIAnalyzer handles AI-powered log insights generation
 type OpenAIAnalyzer struct {
 	client *azopenai.Client
 	config Config
+	cache  *insightsCache
 }
 
 // NewOpenAIAnalyzer creates a new OpenAI log analyzer
 func NewOpenAIAnalyzer(config Config) (*OpenAIAnalyzer, error) {
-	// Validate configuration
-	if config.APIKey == "" || config.DeploymentName == "" || config.Endpoint == "" {
-		return nil, fmt.Errorf("missing required OpenAI configuration")
+	if config.Kind == "" {
+		config.Kind = KindAzure
 	}
 
-	// Create Azure OpenAI client
+	// Validate configuration. The OpenAI API has a fixed endpoint and a
+	// sensible default model, while Azure needs both named.
+	switch config.Kind {
+	case KindAzure:
+		if err := config.missingFields(FieldAPIKey, FieldEndpoint, FieldDeploymentName); err != nil {
+			return nil, err
+		}
+	case KindOpenAI:
+		if err := config.missingFields(FieldAPIKey); err != nil {
+			return nil, err
+		}
+		if config.Endpoint == "" {
+			config.Endpoint = DefaultOpenAIEndpoint
+		}
+		if config.DeploymentName == "" {
+			config.DeploymentName = DefaultOpenAIModel
+		}
+	default:
+		return nil, fmt.Errorf("unsupported OpenAI kind %q, expected %s or %s", config.Kind, KindAzure, KindOpenAI)
+	}
+
+	if config.Logger == nil {
+		config.Logger = zap.NewNop()
+	}
+	if config.SystemPrompt == "" {
+		config.SystemPrompt = config.defaultSystemPrompt()
+	}
+
+	// Create the client; requests name the Azure deployment or OpenAI model
+	// through the same DeploymentName field
 	keyCredential := azcore.NewKeyCredential(config.APIKey)
-	client, err := azopenai.NewClientWithKeyCredential(config.Endpoint, keyCredential, nil)
+	var client *azopenai.Client
+	var err error
+	if config.Kind == KindOpenAI {
+		client, err = azopenai.NewClientForOpenAI(config.Endpoint, keyCredential, nil)
+	} else {
+		client, err = azopenai.NewClientWithKeyCredential(config.Endpoint, keyCredential, nil)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OpenAI client: %w", err)
 	}
 
-	return &OpenAIAnalyzer{
+	oa := &OpenAIAnalyzer{
 		client: client,
 		config: config,
-	}, nil
+	}
+
+	// Cache insights unless disabled; without a cache dir we simply don't cache
+	if !config.NoCache {
+		if dir, err := cacheDir(); err == nil {
+			oa.cache = &insightsCache{dir: dir}
+		}
+	}
+
+	return oa, nil
 }
 
 // GenerateInsights generates AI-powered log analysis insights
 func (oa *OpenAIAnalyzer) GenerateInsights(ctx context.Context, logAnalyzer *LogAnalyzer) (string, error) {
-	// Prepare log texts with more context
-	var criticalLogTexts []string
-	var performanceLogTexts []string
-
-	// Convert log entries to formatted strings
-	for _, log := range logAnalyzer.criticalEvents {
-		criticalLogTexts = append(criticalLogTexts,
-			fmt.Sprintf("%s | %s | %s | %s",
-				log.Timestamp, log.Namespace, log.PodName, log.LogContent,
-			),
-		)
+	enc, err := encodingFor(oa.config.DeploymentName)
+	if err != nil {
+		return "", err
 	}
+	userPrompt := oa.config.userPrompt(enc, logAnalyzer, oa.config.SystemPrompt)
+	return oa.complete(ctx, enc, oa.config.SystemPrompt, userPrompt, nil)
+}
 
-	for _, log := range logAnalyzer.performanceIssues {
-		performanceLogTexts = append(performanceLogTexts,
-			fmt.Sprintf("%s | %s | %s | %s",
-				log.Timestamp, log.Namespace, log.PodName, log.LogContent,
-			),
-		)
+// complete sends the prompts to OpenAI, reusing a cached completion for
+// identical prompts. A non-nil format constrains the response, e.g. to JSON.
+func (oa *OpenAIAnalyzer) complete(ctx context.Context, enc *tiktoken.Tiktoken, systemPrompt, userPrompt string, format azopenai.ChatCompletionsResponseFormatClassification) (string, error) {
+	// Reuse insights previously generated for an identical prompt
+	key := cacheKey(oa.config.DeploymentName, systemPrompt, userPrompt)
+	if oa.cache != nil {
+		if insights, ok := oa.cache.get(key); ok {
+			oa.config.Logger.Debug("using cached insights", zap.String("key", key))
+			return insights, nil
+		}
 	}
 
-	// Include the existing detailed report for additional context
-	detailedReport := logAnalyzer.generateDetailedReport()
-
-	// Combine logs with additional context
-	focusedLogs := fmt.Sprintf("Detailed Report:\n%s\n\nCritical Events:\n%s\n\nPerformance Issues:\n%s",
-		detailedReport,
-		strings.Join(criticalLogTexts, "\n"),
-		strings.Join(performanceLogTexts, "\n"),
+	// Report the expected usage before anything is billed
+	promptTokens := len(enc.EncodeOrdinary(systemPrompt)) + len(enc.EncodeOrdinary(userPrompt))
+	oa.config.Logger.Info("sending prompt to OpenAI",
+		zap.Int("promptTokens", promptTokens),
+		zap.Int("promptBudget", oa.config.promptBudget()),
+		zap.Int("maxCompletionTokens", maxCompletionTokens),
 	)
 
-	// Add a size check to prevent potential issues with very large inputs
-	const maxInputSize = 10000 // Adjust based on your needs
-	if len(focusedLogs) > maxInputSize {
-		focusedLogs = focusedLogs[:maxInputSize]
+	// Bound the request, unless the caller already set a deadline such as
+	// --timeout, which may be longer
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultRequestTimeout)
+		defer cancel()
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
 	// Prepare OpenAI request
 	req := azopenai.ChatCompletionsOptions{
 		Messages: []azopenai.ChatRequestMessageClassification{
 			&azopenai.ChatRequestSystemMessage{
-				Content: azopenai.NewChatRequestSystemMessageContent(AnalysisPrompt),
+				Content: azopenai.NewChatRequestSystemMessageContent(systemPrompt),
 			},
 			&azopenai.ChatRequestUserMessage{
-				Content: azopenai.NewChatRequestUserMessageContent(
-					fmt.Sprintf("Analyze the following Kubernetes log analysis and provide strategic insights and recommendations:\n\n%s", focusedLogs),
-				),
+				Content: azopenai.NewChatRequestUserMessageContent(userPrompt),
 			},
 		},
 		DeploymentName: &oa.config.DeploymentName,
-		MaxTokens:      toInt32Ptr(750), // Increased token limit to prevent truncation
+		MaxTokens:      toInt32Ptr(maxCompletionTokens),
+		ResponseFormat: format,
 	}
 
+	oa.config.Logger.Debug("requesting chat completion",
+		zap.String("deployment", oa.config.DeploymentName),
+		zap.Int("promptBytes", len(userPrompt)),
+	)
 	resp, err := oa.client.GetChatCompletions(ctx, req, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to get chat completions: %w", err)
+		return "", oa.completionError(ctx, err)
 	}
 
 	if len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
-		return *resp.Choices[0].Message.Content, nil
+		insights := *resp.Choices[0].Message.Content
+		if oa.cache != nil {
+			// A failed cache write shouldn't fail the analysis
+			if err := oa.cache.put(key, insights); err != nil {
+				oa.config.Logger.Warn("failed to cache insights", zap.Error(err))
+			}
+		}
+		return insights, nil
 	}
 
 	return "", fmt.Errorf("no insights generated")
 }
 
+// completionError explains a failed chat completion. A misspelled Azure
+// deployment surfaces as a 404, so it's reported with the valid names.
+func (oa *OpenAIAnalyzer) completionError(ctx context.Context, err error) error {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound && oa.config.Kind == KindAzure {
+		if verr := oa.ValidateDeployment(ctx); errors.Is(verr, ErrDeploymentNotFound) {
+			return verr
+		}
+	}
+	return fmt.Errorf("failed to get chat completions: %w", err)
+}
+
+// buildUserPrompt formats the analysis as the user message, trimming the log
+// context to limit tokens
+func buildUserPrompt(enc *tiktoken.Tiktoken, logAnalyzer *LogAnalyzer, contextLines, limit int) string {
+	logAnalyzer.mu.RLock()
+	defer logAnalyzer.mu.RUnlock()
+
+	// Group repeated events into templates with counts, which keeps the signal
+	// in far fewer tokens. Context windows need the raw entries.
+	var criticalLogTexts []string
+	if contextLines > 0 {
+		criticalLogTexts = logAnalyzer.criticalEventTexts(contextLines)
+	} else {
+		criticalLogTexts = logAnalyzer.templateTexts(logAnalyzer.criticalEvents)
+	}
+	performanceLogTexts := logAnalyzer.templateTexts(logAnalyzer.performanceIssues)
+
+	// Findings from custom classifiers, in a stable order
+	categories := make([]string, 0, len(logAnalyzer.customFindings))
+	for category := range logAnalyzer.customFindings {
+		categories = append(categories, string(category))
+	}
+	sort.Strings(categories)
+	var customTexts []string
+	for _, category := range categories {
+		customTexts = append(customTexts, fmt.Sprintf("\n\nCustom Findings: %s:\n%s",
+			category, strings.Join(logAnalyzer.templateTexts(logAnalyzer.customFindings[Category(category)]), "\n")))
+	}
+
+	// Summarize the counts; the events themselves are listed below
+	summary := logAnalyzer.reportSummary()
+
+	// Flag pods whose log rate spiked, which keyword matching can't see
+	var anomalyTexts []string
+	for _, anomaly := range logAnalyzer.detectRateAnomalies(DefaultAnomalyWindow) {
+		anomalyTexts = append(anomalyTexts, anomaly.String())
+	}
+	if len(anomalyTexts) == 0 {
+		anomalyTexts = append(anomalyTexts, "None detected.")
+	}
+
+	// Combine logs with additional context, warning first when the capture
+	// is incomplete so gaps aren't read as quiet periods
+	focusedLogs := fmt.Sprintf("%sSummary:\n%sLog Rate Anomalies:\n%s\n\nCritical Events:\n%s\n\nPerformance Issues:\n%s%s",
+		logAnalyzer.incompleteNote(),
+		summary,
+		strings.Join(anomalyTexts, "\n"),
+		strings.Join(criticalLogTexts, "\n"),
+		strings.Join(performanceLogTexts, "\n"),
+		strings.Join(customTexts, ""),
+	)
+
+	// Keep very large inputs within the model's budget
+	focusedLogs = trimToTokens(enc, focusedLogs, limit)
+
+	return fmt.Sprintf("Analyze the following Kubernetes log analysis and provide strategic insights and recommendations:\n\n%s", focusedLogs)
+}
+
+// buildErrorsPrompt formats only the critical events as the user message,
+// grouped into templates and trimmed to limit tokens
+func buildErrorsPrompt(enc *tiktoken.Tiktoken, logAnalyzer *LogAnalyzer, limit int) string {
+	logAnalyzer.mu.RLock()
+	defer logAnalyzer.mu.RUnlock()
+
+	criticalLogTexts := logAnalyzer.templateTexts(logAnalyzer.criticalEvents)
+	if len(criticalLogTexts) == 0 {
+		criticalLogTexts = append(criticalLogTexts, "None detected.")
+	}
+	events := trimToTokens(enc, logAnalyzer.incompleteNote()+strings.Join(criticalLogTexts, "\n"), limit)
+
+	return fmt.Sprintf("Find the root cause of the following critical events from Kubernetes logs, each given as count | pods | event:\n\n%s", events)
+}
+
 // Helper function to convert int to int32 pointer
 func toInt32Ptr(i int) *int32 {
 	int32Val := int32(i)
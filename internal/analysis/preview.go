@@ -0,0 +1,126 @@
+package analysis
+
+import (
+	"fmt"
+	"hallucino/internal/k8s"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+)
+
+// decision records how analyzeLine classified an entry
+type decision struct {
+	category Category
+	rule     int // position in classifiers, or -1 when none matched
+}
+
+// Classification is the decision made for one entry, for tuning classifiers
+type Classification struct {
+	Entry    k8s.LogEntry
+	Category Category
+	Severity Severity
+	// Rule describes the classifier that matched, e.g. its pattern, and is
+	// empty when none did
+	Rule string
+}
+
+// Classifications returns how each entry was classified, in order
+func (la *LogAnalyzer) Classifications() []Classification {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+
+	classifications := make([]Classification, len(la.logs))
+	for i, log := range la.logs {
+		classifications[i] = Classification{
+			Entry:    log,
+			Category: la.decisions[i].category,
+			Severity: EntrySeverity(log),
+		}
+		if rule := la.decisions[i].rule; rule >= 0 {
+			classifications[i].Rule = ruleName(la.classifiers[rule])
+		}
+	}
+	return classifications
+}
+
+// ruleName describes a classifier for the classification preview
+func ruleName(classifier LineClassifier) string {
+	switch c := classifier.(type) {
+	case RegexClassifier:
+		return c.Pattern.String()
+	case SeverityMapping:
+		return "structured level"
+	default:
+		return fmt.Sprintf("%T", classifier)
+	}
+}
+
+// categoryColors color the preview by category, with custom categories blue
+var categoryColors = map[Category]*color.Color{
+	CategoryError:       color.New(color.FgRed),
+	CategoryWarning:     color.New(color.FgYellow),
+	CategoryPerformance: color.New(color.FgCyan),
+	CategoryRestart:     color.New(color.FgMagenta),
+}
+
+// WriteClassifications prints each entry with its category, severity and the
+// rule that matched, colored by category, followed by the count per category
+func (la *LogAnalyzer) WriteClassifications(w io.Writer) error {
+	classifications := la.Classifications()
+
+	ruleColor := color.New(color.Faint).SprintFunc()
+	counts := map[Category]int{}
+	for _, c := range classifications {
+		counts[c.Category]++
+
+		category := string(c.Category)
+		if c.Category == CategoryNone {
+			category = "-"
+		}
+		sprint := fmt.Sprint
+		if cc, ok := categoryColors[c.Category]; ok {
+			sprint = cc.Sprint
+		} else if c.Category != CategoryNone {
+			sprint = color.New(color.FgBlue).Sprint
+		}
+
+		line := fmt.Sprintf("%-11s %-8s %s/%s | %s", category, c.Severity, c.Entry.PodName, c.Entry.Container,
+			strings.ReplaceAll(c.Entry.LogContent, "\n", " ⏎ "))
+		if c.Rule != "" {
+			line = sprint(line) + "  " + ruleColor("← "+c.Rule)
+		} else {
+			line = sprint(line)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	// Built-in categories first, then custom ones by name
+	order := []Category{CategoryError, CategoryWarning, CategoryPerformance, CategoryRestart}
+	var custom []string
+	for category := range counts {
+		if _, builtIn := categoryColors[category]; !builtIn && category != CategoryNone {
+			custom = append(custom, string(category))
+		}
+	}
+	sort.Strings(custom)
+	for _, category := range custom {
+		order = append(order, Category(category))
+	}
+	order = append(order, CategoryNone)
+
+	fmt.Fprintf(w, "\nClassified %d entries:\n", len(classifications))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, category := range order {
+		name := string(category)
+		if category == CategoryNone {
+			name = "unclassified"
+		}
+		fmt.Fprintf(tw, "  %s\t%d\n", name, counts[category])
+	}
+	return tw.Flush()
+}
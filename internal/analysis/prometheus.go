@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// prometheusMetrics describes the metrics exposed by WritePrometheus
+var prometheusMetrics = []struct {
+	name  string
+	help  string
+	value func(Counts) int
+}{
+	{"hallucino_log_errors_total", "Number of log lines classified as errors.", func(c Counts) int { return c.Errors }},
+	{"hallucino_log_warnings_total", "Number of log lines classified as warnings.", func(c Counts) int { return c.Warnings }},
+	{"hallucino_performance_issues_total", "Number of log lines indicating performance issues.", func(c Counts) int { return c.PerformanceIssues }},
+}
+
+// WritePrometheus writes the analyzer counts in the Prometheus text exposition
+// format, labeled by namespace and pod
+func (la *LogAnalyzer) WritePrometheus(w io.Writer) error {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+
+	pods := make([]podKey, 0, len(la.podStats))
+	for pod := range la.podStats {
+		pods = append(pods, pod)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].namespace != pods[j].namespace {
+			return pods[i].namespace < pods[j].namespace
+		}
+		return pods[i].pod < pods[j].pod
+	})
+
+	for _, metric := range prometheusMetrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name); err != nil {
+			return err
+		}
+		for _, pod := range pods {
+			if _, err := fmt.Fprintf(w, "%s{namespace=\"%s\",pod=\"%s\"} %d\n",
+				metric.name,
+				escapeLabelValue(pod.namespace),
+				escapeLabelValue(pod.pod),
+				metric.value(la.podStats[pod]),
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// escapeLabelValue escapes a label value per the text exposition format
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
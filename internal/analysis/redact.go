@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// redactedPlaceholder replaces masked content
+const redactedPlaceholder = "[REDACTED]"
+
+// DefaultRedactPatterns match common secrets and personal data
+var DefaultRedactPatterns = []string{
+	`(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*`,                                      // bearer tokens
+	`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,                       // JWTs
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,                                           // AWS access key IDs
+	`(?i)(?:password|passwd|pwd|secret|token|api[_-]?key)\s*[=:]\s*[^\s,;&]+`, // key=value credentials
+	`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,                        // email addresses
+	`\b(?:\d{1,3}\.){3}\d{1,3}\b`,                                             // IPv4 addresses
+}
+
+// Redactor masks sensitive content in log lines
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor creates a redactor using the default patterns plus any extra
+// user-supplied regular expressions
+func NewRedactor(extra []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, pattern := range append(append([]string{}, DefaultRedactPatterns...), extra...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redact masks every match of the redactor's patterns. Overlapping matches from
+// different patterns are merged so they're replaced by a single placeholder.
+func (r *Redactor) Redact(content string) string {
+	var spans [][]int
+	for _, re := range r.patterns {
+		spans = append(spans, re.FindAllStringIndex(content, -1)...)
+	}
+	if len(spans) == 0 {
+		return content
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	redacted := make([]byte, 0, len(content))
+	pos := 0
+	for i := 0; i < len(spans); {
+		start, end := spans[i][0], spans[i][1]
+		for i++; i < len(spans) && spans[i][0] <= end; i++ {
+			end = max(end, spans[i][1])
+		}
+		redacted = append(redacted, content[pos:start]...)
+		redacted = append(redacted, redactedPlaceholder...)
+		pos = end
+	}
+	redacted = append(redacted, content[pos:]...)
+
+	return string(redacted)
+}
@@ -0,0 +1,62 @@
+package analysis
+
+import (
+	"hallucino/internal/k8s"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RevisionLogs holds the entries retrieved from the pods of one Deployment
+// revision
+type RevisionLogs struct {
+	Revision string
+	Logs     []k8s.LogEntry
+}
+
+// SplitByRevision groups entries by their Deployment revision, newest first.
+// Entries without a revision are left out.
+func SplitByRevision(logs []k8s.LogEntry) []RevisionLogs {
+	byRevision := map[string][]k8s.LogEntry{}
+	for _, log := range logs {
+		if log.Revision != "" {
+			byRevision[log.Revision] = append(byRevision[log.Revision], log)
+		}
+	}
+
+	revisions := make([]RevisionLogs, 0, len(byRevision))
+	for revision, logs := range byRevision {
+		revisions = append(revisions, RevisionLogs{Revision: revision, Logs: logs})
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return newerRevision(revisions[i].Revision, revisions[j].Revision)
+	})
+	return revisions
+}
+
+// newerRevision reports whether revision a is newer than b. Revisions are
+// numbered, so "10" is newer than "9"; anything else compares as text.
+func newerRevision(a, b string) bool {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return na > nb
+	}
+	return a > b
+}
+
+// RegressionReport lists the critical events that are new compared with
+// baseline, see NewCriticalEvents, as Markdown list items. It returns an empty
+// string when there are none.
+func (la *LogAnalyzer) RegressionReport(baseline *LogAnalyzer) string {
+	events := NewCriticalEvents(baseline, la)
+
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+
+	var report strings.Builder
+	for _, log := range events {
+		la.writeReportLine(&report, log, la.timestampFormat)
+	}
+	return report.String()
+}
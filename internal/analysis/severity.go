@@ -0,0 +1,136 @@
+package analysis
+
+import (
+	"fmt"
+	"hallucino/internal/k8s"
+	"regexp"
+	"strings"
+)
+
+// Severity is a normalized log level
+type Severity int
+
+// Severities, from least to most severe
+const (
+	SeverityUnknown Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityUnknown:  "unknown",
+	SeverityDebug:    "debug",
+	SeverityInfo:     "info",
+	SeverityWarning:  "warning",
+	SeverityError:    "error",
+	SeverityCritical: "critical",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity returns the severity with the given name, e.g. "warning"
+func ParseSeverity(name string) (Severity, error) {
+	for severity, n := range severityNames {
+		if severity != SeverityUnknown && strings.EqualFold(n, name) {
+			return severity, nil
+		}
+	}
+	return SeverityUnknown, fmt.Errorf("unknown severity %q, must be one of debug, info, warning, error, critical", name)
+}
+
+// SeverityMapping reads an entry's level from the structure of the line rather
+// than from keywords anywhere in it, so an info line mentioning "error" isn't
+// counted as an error
+type SeverityMapping struct {
+	// Formats locate the level token, which is the first capture group. The
+	// first format that matches is used.
+	Formats []*regexp.Regexp
+	// Levels maps lower-cased level tokens to severities. Tokens that aren't
+	// listed leave the severity unknown.
+	Levels map[string]Severity
+}
+
+// DefaultSeverityMapping understands the level formats of zap, logrus, klog,
+// logfmt and the numeric levels used by pino and bunyan
+var DefaultSeverityMapping = SeverityMapping{
+	Formats: []*regexp.Regexp{
+		// JSON: {"level":"error"} (zap, logrus) or {"level":50} (pino, bunyan)
+		regexp.MustCompile(`(?i)"(?:level|lvl|severity)"\s*:\s*"?(\w+)`),
+		// logfmt: level=error (logrus text formatter)
+		regexp.MustCompile(`(?i)\b(?:level|lvl|severity)=(\w+)`),
+		// logrus TTY formatter: ERRO[0000] message
+		regexp.MustCompile(`^([A-Z]{4})\[\d+\]`),
+		// klog: E0102 15:04:05.000000 ...
+		regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}`),
+		// zap console encoder: 2024-01-02T15:04:05.000Z<TAB>ERROR<TAB>message
+		regexp.MustCompile(`^\S+\t([A-Z]+)\t`),
+	},
+	Levels: map[string]Severity{
+		"trace": SeverityDebug, "trac": SeverityDebug, "debug": SeverityDebug, "debu": SeverityDebug,
+		"10": SeverityDebug, "20": SeverityDebug,
+		"info": SeverityInfo, "i": SeverityInfo, "30": SeverityInfo,
+		"warn": SeverityWarning, "warning": SeverityWarning, "w": SeverityWarning, "40": SeverityWarning,
+		"error": SeverityError, "erro": SeverityError, "err": SeverityError, "e": SeverityError, "50": SeverityError,
+		"fatal": SeverityCritical, "fata": SeverityCritical, "f": SeverityCritical, "60": SeverityCritical,
+		"panic": SeverityCritical, "pani": SeverityCritical, "dpanic": SeverityCritical,
+		"critical": SeverityCritical, "crit": SeverityCritical,
+	},
+}
+
+// Severity returns the level of the entry's content, or SeverityUnknown when
+// no format matches or the token isn't mapped
+func (m SeverityMapping) Severity(content string) Severity {
+	for _, format := range m.Formats {
+		if match := format.FindStringSubmatch(content); len(match) > 1 {
+			return m.Levels[strings.ToLower(match[1])]
+		}
+	}
+	return SeverityUnknown
+}
+
+// criticalKeywords mark lines without a structured level as critical errors
+var criticalKeywords = regexp.MustCompile(`(?i)critical|fatal|panic`)
+
+// EntrySeverity returns the entry's structured level. Lines without one are
+// judged by the built-in classifiers instead: errors, which are critical when
+// they mention critical, fatal or panic, warnings, and info for the rest.
+func EntrySeverity(log k8s.LogEntry) Severity {
+	if severity := DefaultSeverityMapping.Severity(log.LogContent); severity != SeverityUnknown {
+		return severity
+	}
+	switch classify(log, DefaultClassifiers) {
+	case CategoryError:
+		if criticalKeywords.MatchString(log.LogContent) {
+			return SeverityCritical
+		}
+		return SeverityError
+	case CategoryWarning:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// Classify implements LineClassifier. Errors and warnings are classified by
+// level alone. Lower levels can still be performance or restart findings but
+// are never errors or warnings, whatever their text says.
+func (m SeverityMapping) Classify(log k8s.LogEntry) (string, bool) {
+	switch m.Severity(log.LogContent) {
+	case SeverityCritical, SeverityError:
+		return string(CategoryError), true
+	case SeverityWarning:
+		return string(CategoryWarning), true
+	case SeverityInfo, SeverityDebug:
+		return string(classify(log, []LineClassifier{DefaultPerformanceClassifier, restartClassifier})), true
+	default:
+		return "", false
+	}
+}
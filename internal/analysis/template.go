@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"fmt"
+	"hallucino/internal/k8s"
+	"sort"
+	"strings"
+)
+
+// maxPromptTemplates bounds the templates listed per section of the AI prompt
+const maxPromptTemplates = 50
+
+// Template is a group of entries that are the same event apart from
+// timestamps, IDs, IP addresses and numbers, see NormalizeContent
+type Template struct {
+	Template string
+	Count    int
+	// Example is the first entry in the group
+	Example k8s.LogEntry
+	// Pods lists the "namespace/pod" names the event came from, in the order seen
+	Pods []string
+}
+
+// String formats the template with its count and pods, e.g.
+// "12x | prod/api-1, prod/api-2 | user <n> failed"
+func (t Template) String() string {
+	pods := t.Pods
+	suffix := ""
+	if len(pods) > 3 {
+		pods, suffix = pods[:3], fmt.Sprintf(" (+%d more)", len(t.Pods)-3)
+	}
+	return fmt.Sprintf("%dx | %s%s | %s", t.Count, strings.Join(pods, ", "), suffix, t.Template)
+}
+
+// Templatize groups all entries by template, most frequent first
+func (la *LogAnalyzer) Templatize() []Template {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	return la.templatize(la.logs)
+}
+
+// templatize groups the entries by the template of their reported content,
+// ordering by count and then by first appearance
+func (la *LogAnalyzer) templatize(logs []k8s.LogEntry) []Template {
+	var templates []Template
+	index := map[string]int{}
+	pods := map[string]map[string]bool{}
+	for _, log := range logs {
+		key := NormalizeContent(la.content(log))
+		i, ok := index[key]
+		if !ok {
+			i = len(templates)
+			index[key] = i
+			templates = append(templates, Template{Template: key, Example: log})
+			pods[key] = map[string]bool{}
+		}
+		templates[i].Count++
+
+		pod := log.Namespace + "/" + log.PodName
+		if !pods[key][pod] {
+			pods[key][pod] = true
+			templates[i].Pods = append(templates[i].Pods, pod)
+		}
+	}
+
+	sort.SliceStable(templates, func(i, j int) bool { return templates[i].Count > templates[j].Count })
+	return templates
+}
+
+// templateTexts formats the most frequent templates of the entries for the
+// AI prompt, noting how many less frequent ones were left out
+func (la *LogAnalyzer) templateTexts(logs []k8s.LogEntry) []string {
+	templates := la.templatize(logs)
+
+	var texts []string
+	for i, template := range templates {
+		if i == maxPromptTemplates {
+			texts = append(texts, fmt.Sprintf("... %d less frequent templates omitted", len(templates)-i))
+			break
+		}
+		texts = append(texts, template.String())
+	}
+	return texts
+}
@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultTimelineBucket is the bucket size used by --timeline when none is given
+const DefaultTimelineBucket = time.Minute
+
+// MaxTimelineBuckets caps how many buckets a timeline spans, so a capture
+// covering days at a small bucket size still fits on one line per pod
+const MaxTimelineBuckets = 120
+
+// sparkBars draw bucket counts scaled to the busiest bucket, with quiet
+// buckets left blank so bursts stand out
+var sparkBars = []rune(" ▁▂▃▄▅▆▇█")
+
+// Timeline counts each pod's errors in consecutive buckets of the given size,
+// keyed by "namespace/pod". Every pod's counts span the same buckets, from the
+// first error of any pod to the last, so they can be compared side by side.
+// When that would take more than MaxTimelineBuckets the bucket is widened to a
+// multiple of its size that fits, and the size used is returned with the counts.
+// Pods without errors and entries with unparseable timestamps are left out.
+func (la *LogAnalyzer) Timeline(bucket time.Duration) (time.Duration, map[string][]int) {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+	_, bucket, counts := la.timeline(bucket)
+	return bucket, counts
+}
+
+// timeline returns the start of the first bucket and the bucket size used
+// along with the counts
+func (la *LogAnalyzer) timeline(bucket time.Duration) (time.Time, time.Duration, map[string][]int) {
+	if bucket <= 0 {
+		return time.Time{}, bucket, nil
+	}
+
+	type errorAt struct {
+		pod string
+		at  time.Time
+	}
+	var found []errorAt
+	var earliest, latest time.Time
+	for _, log := range la.logs {
+		if classify(log, la.classifiers) != CategoryError {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, log.Timestamp)
+		if err != nil {
+			continue
+		}
+		if earliest.IsZero() || t.Before(earliest) {
+			earliest = t
+		}
+		if t.After(latest) {
+			latest = t
+		}
+		found = append(found, errorAt{pod: log.Namespace + "/" + log.PodName, at: t})
+	}
+	if len(found) == 0 {
+		return time.Time{}, bucket, nil
+	}
+
+	// Widen the bucket until the span fits, rechecking since truncating to the
+	// wider bucket can add one at the start
+	first := earliest.Truncate(bucket)
+	buckets := int(latest.Truncate(bucket).Sub(first)/bucket) + 1
+	for buckets > MaxTimelineBuckets {
+		bucket *= time.Duration((buckets + MaxTimelineBuckets - 1) / MaxTimelineBuckets)
+		first = earliest.Truncate(bucket)
+		buckets = int(latest.Truncate(bucket).Sub(first)/bucket) + 1
+	}
+
+	counts := map[string][]int{}
+	for _, e := range found {
+		if counts[e.pod] == nil {
+			counts[e.pod] = make([]int, buckets)
+		}
+		counts[e.pod][int(e.at.Truncate(bucket).Sub(first)/bucket)]++
+	}
+	return first, bucket, counts
+}
+
+// WriteTimeline charts each pod's errors over time as a sparkline, one
+// character per bucket, scaled to the busiest bucket of any pod. The bucket is
+// widened as in Timeline when the span would need more than MaxTimelineBuckets.
+func (la *LogAnalyzer) WriteTimeline(w io.Writer, bucket time.Duration) error {
+	la.mu.RLock()
+	defer la.mu.RUnlock()
+
+	start, bucket, counts := la.timeline(bucket)
+	if len(counts) == 0 {
+		_, err := fmt.Fprintln(w, "No errors with timestamps to chart.")
+		return err
+	}
+
+	pods := make([]string, 0, len(counts))
+	peak := 0
+	var buckets int
+	for pod, podCounts := range counts {
+		pods = append(pods, pod)
+		buckets = len(podCounts)
+		for _, n := range podCounts {
+			peak = max(peak, n)
+		}
+	}
+	sort.Strings(pods)
+
+	end := start.Add(time.Duration(buckets) * bucket)
+	fmt.Fprintf(w, "Errors per %s from %s to %s (peak %d per bucket):\n\n",
+		bucket, start.Format(time.RFC3339), end.Format(time.RFC3339), peak)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POD\tERRORS\tTIMELINE")
+	for _, pod := range pods {
+		total := 0
+		line := make([]rune, len(counts[pod]))
+		for i, n := range counts[pod] {
+			total += n
+			line[i] = sparkBar(n, peak)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t|%s|\n", pod, total, string(line))
+	}
+	return tw.Flush()
+}
+
+// sparkBar returns the bar for a count, showing any non-zero count with at
+// least the lowest bar
+func sparkBar(n, peak int) rune {
+	if n == 0 || peak == 0 {
+		return sparkBars[0]
+	}
+	levels := len(sparkBars) - 1
+	return sparkBars[1+(n*levels-1)/peak]
+}
@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"hallucino/internal/k8s"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimelineWidensBucket(t *testing.T) {
+	tests := []struct {
+		name       string
+		timestamps []string
+		wantBucket time.Duration
+		wantCounts []int
+	}{
+		{
+			name:       "span fits",
+			timestamps: []string{"2024-11-27T10:00:30Z", "2024-11-27T10:02:10Z"},
+			wantBucket: time.Minute,
+			wantCounts: []int{1, 0, 1},
+		},
+		{
+			name:       "span of days",
+			timestamps: []string{"2024-11-25T10:00:00Z", "2024-11-27T10:00:00Z"},
+			wantBucket: 25 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs []k8s.LogEntry
+			for _, ts := range tt.timestamps {
+				logs = append(logs, k8s.LogEntry{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: ts, LogContent: "ERROR: connection refused"})
+			}
+			bucket, counts := NewLogAnalyzer(logs).Timeline(time.Minute)
+			if bucket != tt.wantBucket {
+				t.Errorf("Timeline() bucket = %s, want %s", bucket, tt.wantBucket)
+			}
+			got := counts["prod/api-1"]
+			if len(got) > MaxTimelineBuckets {
+				t.Errorf("Timeline() spans %d buckets, want at most %d", len(got), MaxTimelineBuckets)
+			}
+			total := 0
+			for _, n := range got {
+				total += n
+			}
+			if total != len(tt.timestamps) {
+				t.Errorf("Timeline() counted %d errors, want %d", total, len(tt.timestamps))
+			}
+			if tt.wantCounts != nil && !reflect.DeepEqual(got, tt.wantCounts) {
+				t.Errorf("Timeline() counts = %v, want %v", got, tt.wantCounts)
+			}
+		})
+	}
+}
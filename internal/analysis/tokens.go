@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+const (
+	// defaultEncoding is used when the deployment name isn't a model tiktoken knows,
+	// which is common since Azure deployments are named by the user
+	defaultEncoding = "cl100k_base"
+
+	// defaultContextWindow is assumed for models and deployments whose context
+	// window isn't known, matching the smallest current GPT-4 window
+	defaultContextWindow = 8192
+
+	// promptOverheadTokens is reserved in the budget for the instructions
+	// framing the logs in the user message, any trace note and the chat
+	// message formatting
+	promptOverheadTokens = 200
+
+	// maxCompletionTokens bounds the length of the generated insights
+	maxCompletionTokens = 750
+)
+
+// contextWindows are the context windows of known models in tokens, by name
+// prefix, most specific first. Azure deployments named after their model,
+// e.g. gpt-4o-prod, match too.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"gpt-35-turbo", 16385},
+	{"o1", 128000},
+	{"o3", 200000},
+}
+
+func init() {
+	// Use the embedded BPE ranks rather than downloading them at runtime
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// encodingFor returns the tokenizer for a model or deployment name
+func encodingFor(model string) (*tiktoken.Tiktoken, error) {
+	if enc, err := tiktoken.EncodingForModel(model); err == nil {
+		return enc, nil
+	}
+
+	enc, err := tiktoken.GetEncoding(defaultEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokenizer: %w", err)
+	}
+	return enc, nil
+}
+
+// contextWindow returns the context window of a model or deployment name,
+// defaultContextWindow when it isn't known
+func contextWindow(model string) int {
+	for _, window := range contextWindows {
+		if strings.HasPrefix(model, window.prefix) {
+			return window.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+// promptBudget returns the maximum tokens of the system and user messages
+// together: Config.ContextBudget when set, otherwise the model's context
+// window less the tokens reserved for the completion
+func (c Config) promptBudget() int {
+	if c.ContextBudget > 0 {
+		return c.ContextBudget
+	}
+	return contextWindow(c.DeploymentName) - maxCompletionTokens
+}
+
+// logTokenLimit returns the tokens left for log context in the user message
+// once the system prompt and promptOverheadTokens are taken from the budget
+func (c Config) logTokenLimit(enc *tiktoken.Tiktoken, systemPrompt string) int {
+	return max(c.promptBudget()-len(enc.EncodeOrdinary(systemPrompt))-promptOverheadTokens, 0)
+}
+
+// trimToTokens shortens text to at most limit tokens, cutting at the end of
+// the last whole line that fits so entries aren't sent half-finished. A
+// single line longer than the limit is cut at a token boundary.
+func trimToTokens(enc *tiktoken.Tiktoken, text string, limit int) string {
+	tokens := enc.EncodeOrdinary(text)
+	if len(tokens) <= limit {
+		return text
+	}
+	trimmed := enc.Decode(tokens[:limit])
+	if i := strings.LastIndexByte(trimmed, '\n'); i > 0 {
+		trimmed = trimmed[:i]
+	}
+	return trimmed
+}
+
+// Estimate is the approximate token usage of an insights request
+type Estimate struct {
+	PromptTokens        int
+	MaxCompletionTokens int
+	// PromptBudget is the most prompt tokens the request could use, beyond
+	// which the logs are trimmed
+	PromptBudget int
+}
+
+// EstimateTokens estimates the tokens GenerateInsights would send for the given
+// analysis without calling OpenAI, so it needs no credentials
+func EstimateTokens(logAnalyzer *LogAnalyzer, config Config) (Estimate, error) {
+	if config.SystemPrompt == "" {
+		config.SystemPrompt = config.defaultSystemPrompt()
+	}
+
+	if config.Kind == KindOpenAI && config.DeploymentName == "" {
+		config.DeploymentName = DefaultOpenAIModel
+	}
+
+	enc, err := encodingFor(config.DeploymentName)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	userPrompt := config.userPrompt(enc, logAnalyzer, config.SystemPrompt)
+	return Estimate{
+		PromptTokens:        len(enc.EncodeOrdinary(config.SystemPrompt)) + len(enc.EncodeOrdinary(userPrompt)),
+		MaxCompletionTokens: maxCompletionTokens,
+		PromptBudget:        config.promptBudget(),
+	}, nil
+}
@@ -0,0 +1,242 @@
+// Package export sends captured logs to external observability systems
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hallucino/internal/analysis"
+	"hallucino/internal/k8s"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultOTLPEndpoint is the logs path of a local collector's OTLP/HTTP receiver
+const DefaultOTLPEndpoint = "http://localhost:4318/v1/logs"
+
+// DefaultOTLPBatchSize is the number of log records sent per request, which
+// keeps requests well below collectors' default size limits
+const DefaultOTLPBatchSize = 1000
+
+// scopeName identifies hallucino as the producer of the exported records
+const scopeName = "hallucino"
+
+// OTLP severity numbers for the start of each level's range
+const (
+	otlpSeverityDebug = 5
+	otlpSeverityInfo  = 9
+	otlpSeverityWarn  = 13
+	otlpSeverityError = 17
+	otlpSeverityFatal = 21
+)
+
+// OTLPLogs is the body of an OTLP/HTTP logs export request, in the protocol's
+// JSON encoding
+type OTLPLogs struct {
+	ResourceLogs []ResourceLogs `json:"resourceLogs"`
+}
+
+// ResourceLogs holds the records of one container, identified by its resource
+// attributes
+type ResourceLogs struct {
+	Resource  Resource    `json:"resource"`
+	ScopeLogs []ScopeLogs `json:"scopeLogs"`
+}
+
+// Resource describes the source of a set of records
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// ScopeLogs holds records produced by one instrumentation scope
+type ScopeLogs struct {
+	Scope      Scope       `json:"scope"`
+	LogRecords []LogRecord `json:"logRecords"`
+}
+
+// Scope names the instrumentation scope
+type Scope struct {
+	Name string `json:"name"`
+}
+
+// LogRecord is a single log entry. Times are nanoseconds since the Unix epoch,
+// which the JSON encoding represents as strings.
+type LogRecord struct {
+	TimeUnixNano         string   `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string   `json:"observedTimeUnixNano"`
+	SeverityNumber       int      `json:"severityNumber,omitempty"`
+	SeverityText         string   `json:"severityText,omitempty"`
+	Body                 AnyValue `json:"body"`
+}
+
+// KeyValue is an attribute
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is an attribute or body value. Only strings are exported.
+type AnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// ToOTLP converts entries into OTLP log records, grouped by container with the
+// namespace, pod, container and any pod labels as resource attributes. Levels
+// come from analysis.EntrySeverity and the observed time is the given time.
+// Entries whose timestamps can't be parsed are sent without one.
+func ToOTLP(logs []k8s.LogEntry, observed time.Time) OTLPLogs {
+	observedNanos := strconv.FormatInt(observed.UnixNano(), 10)
+
+	// Group by container, keeping the order containers were first seen
+	index := map[string]int{}
+	request := OTLPLogs{ResourceLogs: []ResourceLogs{}}
+	for _, log := range logs {
+		key := log.Namespace + "/" + log.PodName + "/" + log.Container
+		i, ok := index[key]
+		if !ok {
+			i = len(request.ResourceLogs)
+			index[key] = i
+			request.ResourceLogs = append(request.ResourceLogs, ResourceLogs{
+				Resource:  Resource{Attributes: resourceAttributes(log)},
+				ScopeLogs: []ScopeLogs{{Scope: Scope{Name: scopeName}}},
+			})
+		}
+
+		severity := analysis.EntrySeverity(log)
+		record := LogRecord{
+			ObservedTimeUnixNano: observedNanos,
+			SeverityNumber:       otlpSeverity(severity),
+			SeverityText:         severity.String(),
+			Body:                 AnyValue{StringValue: log.LogContent},
+		}
+		if t, err := time.Parse(time.RFC3339Nano, log.Timestamp); err == nil {
+			record.TimeUnixNano = strconv.FormatInt(t.UnixNano(), 10)
+		}
+
+		scope := &request.ResourceLogs[i].ScopeLogs[0]
+		scope.LogRecords = append(scope.LogRecords, record)
+	}
+	return request
+}
+
+// resourceAttributes describes an entry's container using the Kubernetes
+// semantic conventions
+func resourceAttributes(log k8s.LogEntry) []KeyValue {
+	attributes := []KeyValue{
+		{Key: "k8s.namespace.name", Value: AnyValue{StringValue: log.Namespace}},
+		{Key: "k8s.pod.name", Value: AnyValue{StringValue: log.PodName}},
+		{Key: "k8s.container.name", Value: AnyValue{StringValue: log.Container}},
+	}
+
+	labels := make([]string, 0, len(log.Labels))
+	for label := range log.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		attributes = append(attributes, KeyValue{Key: "k8s.pod.label." + label, Value: AnyValue{StringValue: log.Labels[label]}})
+	}
+	return attributes
+}
+
+// otlpSeverity maps a level to its OTLP severity number, leaving unknown
+// levels unspecified
+func otlpSeverity(severity analysis.Severity) int {
+	switch severity {
+	case analysis.SeverityDebug:
+		return otlpSeverityDebug
+	case analysis.SeverityInfo:
+		return otlpSeverityInfo
+	case analysis.SeverityWarning:
+		return otlpSeverityWarn
+	case analysis.SeverityError:
+		return otlpSeverityError
+	case analysis.SeverityCritical:
+		return otlpSeverityFatal
+	default:
+		return 0
+	}
+}
+
+// OTLPExporter sends log records to an OTLP/HTTP endpoint
+type OTLPExporter struct {
+	// Endpoint is the full URL of the logs receiver, e.g. DefaultOTLPEndpoint
+	Endpoint string
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+	// BatchSize is the number of records per request, DefaultOTLPBatchSize
+	// when zero
+	BatchSize int
+	// Client sends the requests, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// Export sends the entries in batches, stopping at the first request that
+// fails or whose records the collector rejects
+func (e *OTLPExporter) Export(ctx context.Context, logs []k8s.LogEntry) error {
+	batchSize := e.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultOTLPBatchSize
+	}
+
+	observed := time.Now()
+	for start := 0; start < len(logs); start += batchSize {
+		end := min(start+batchSize, len(logs))
+		if err := e.send(ctx, ToOTLP(logs[start:end], observed)); err != nil {
+			return fmt.Errorf("failed to export entries %d-%d of %d: %w", start+1, end, len(logs), err)
+		}
+	}
+	return nil
+}
+
+// send posts a single export request
+func (e *OTLPExporter) send(ctx context.Context, logs OTLPLogs) error {
+	body, err := json.Marshal(logs)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Keep error bodies short, they may be whole HTML pages
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector responded %s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+
+	// A successful response can still report records the collector dropped
+	var result struct {
+		PartialSuccess struct {
+			// 64-bit integers may be encoded as strings or numbers
+			RejectedLogRecords json.Number `json:"rejectedLogRecords"`
+			ErrorMessage       string      `json:"errorMessage"`
+		} `json:"partialSuccess"`
+	}
+	if json.Unmarshal(respBody, &result) == nil {
+		if rejected, _ := result.PartialSuccess.RejectedLogRecords.Int64(); rejected > 0 {
+			return fmt.Errorf("collector rejected %d records: %s", rejected, result.PartialSuccess.ErrorMessage)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Permission is an action on a resource, optionally within a namespace, as
+// checked by RBAC
+type Permission struct {
+	// Namespace is empty for all namespaces or cluster-scoped resources
+	Namespace string
+	Verb      string
+	// Group is the API group, empty for the core group
+	Group       string
+	Resource    string
+	Subresource string
+}
+
+// resource names the resource as kubectl does, e.g. pods/log or
+// deployments.apps
+func (p Permission) resource() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	return resource
+}
+
+// String names the permission as kubectl auth can-i does, e.g. "get pods/log
+// in namespace prod"
+func (p Permission) String() string {
+	if p.Namespace == "" {
+		return p.Verb + " " + p.resource()
+	}
+	return fmt.Sprintf("%s %s in namespace %s", p.Verb, p.resource(), p.Namespace)
+}
+
+// CheckAccess asks the API server whether the current user or service account
+// has the permission, returning the authorizer's reason when it's denied
+func CheckAccess(ctx context.Context, client kubernetes.Interface, p Permission) (bool, string, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   p.Namespace,
+				Verb:        p.Verb,
+				Group:       p.Group,
+				Resource:    p.Resource,
+				Subresource: p.Subresource,
+			},
+		},
+	}
+	result, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to check whether the user can %s: %w", p, err)
+	}
+	return result.Status.Allowed, result.Status.Reason, nil
+}
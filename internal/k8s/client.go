@@ -2,87 +2,275 @@ package k8s
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"io"
 	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
 type LogEntry struct {
-	Namespace  string
-	PodName    string
-	Container  string
-	LogContent string
-	Timestamp  string
+	Namespace     string `json:"namespace"`
+	PodName       string `json:"pod"`
+	Container     string `json:"container"`
+	LogContent    string `json:"content"`
+	Timestamp     string `json:"timestamp"`
+	InitContainer bool   `json:"initContainer,omitempty"`
+	// Labels holds the pod labels selected for display, if any
+	Labels map[string]string `json:"labels,omitempty"`
+	// Source names the capture the entry was loaded from when several are
+	// merged, e.g. "prod.ndjson"
+	Source string `json:"source,omitempty"`
+	// Revision is the Deployment revision of the ReplicaSet that owns the
+	// pod, when retrieved with revisions, e.g. "7"
+	Revision string `json:"revision,omitempty"`
+}
+
+// Container identifies a container within a pod
+type Container struct {
+	Name string
+	Init bool
+	// Started is false for containers that have never run, such as those in
+	// a pending pod, which have no logs to retrieve
+	Started bool
+	// Restarts is the number of times the container has restarted
+	Restarts int32
+}
+
+// PodListOptions controls how pods are enumerated
+type PodListOptions struct {
+	// PageSize is the number of pods fetched per request (0 for a single unbounded request)
+	PageSize int64
+	// FieldSelector restricts pods by field, e.g. status.phase=Running
+	FieldSelector string
 }
 
 // ListPods retrieves all pod names in a given namespace
-func ListPods(client *kubernetes.Clientset, namespace string) ([]string, error) {
-	podList, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		return nil, err
-	}
+func ListPods(ctx context.Context, client kubernetes.Interface, namespace string, opts PodListOptions) ([]string, error) {
+	return listPodNames(ctx, client, namespace, metav1.ListOptions{
+		Limit:         opts.PageSize,
+		FieldSelector: opts.FieldSelector,
+	})
+}
 
+// listPodNames lists matching pod names, following continue tokens until all
+// pages have been fetched
+func listPodNames(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) ([]string, error) {
 	var podNames []string
-	for _, pod := range podList.Items {
-		podNames = append(podNames, pod.Name)
+	for {
+		podList, err := client.CoreV1().Pods(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "list", Resource: "pods"}, err)
+		}
+
+		for _, pod := range podList.Items {
+			podNames = append(podNames, pod.Name)
+		}
+
+		if podList.Continue == "" {
+			return podNames, nil
+		}
+		opts.Continue = podList.Continue
 	}
+}
 
-	return podNames, nil
+// ListNamespaces retrieves the names of namespaces matching a label selector,
+// or of all namespaces when the selector is empty
+func ListNamespaces(ctx context.Context, client kubernetes.Interface, labelSelector string) ([]string, error) {
+	opts := metav1.ListOptions{LabelSelector: labelSelector}
+	var names []string
+	for {
+		namespaceList, err := client.CoreV1().Namespaces().List(ctx, opts)
+		if err != nil {
+			return nil, forbidden(Permission{Verb: "list", Resource: "namespaces"}, err)
+		}
+
+		for _, namespace := range namespaceList.Items {
+			names = append(names, namespace.Name)
+		}
+
+		if namespaceList.Continue == "" {
+			return names, nil
+		}
+		opts.Continue = namespaceList.Continue
+	}
 }
 
-// ListContainers retrieves all container names for a specific pod
-func ListContainers(client *kubernetes.Clientset, namespace, podName string) ([]string, error) {
-	pod, err := client.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+// ListContainers retrieves all containers for a specific pod, including ephemeral
+// debug containers and, when includeInit is set, init containers, along with
+// the pod itself for its labels and status. A missing pod is reported as a
+// *PodNotFoundError.
+func ListContainers(ctx context.Context, client kubernetes.Interface, namespace, podName string, includeInit bool) ([]Container, *corev1.Pod, error) {
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
-		return nil, err
+		return nil, nil, podError(namespace, podName, err)
+	}
+
+	started := map[string]bool{}
+	for _, statuses := range [][]corev1.ContainerStatus{
+		pod.Status.InitContainerStatuses,
+		pod.Status.ContainerStatuses,
+		pod.Status.EphemeralContainerStatuses,
+	} {
+		for _, status := range statuses {
+			started[status.Name] = hasStarted(status)
+		}
 	}
+	restarts := RestartCounts(pod)
 
-	var containerNames []string
+	var containers []Container
+	if includeInit {
+		for _, container := range pod.Spec.InitContainers {
+			containers = append(containers, Container{Name: container.Name, Init: true, Started: started[container.Name], Restarts: restarts[container.Name]})
+		}
+	}
 	for _, container := range pod.Spec.Containers {
-		containerNames = append(containerNames, container.Name)
+		containers = append(containers, Container{Name: container.Name, Started: started[container.Name], Restarts: restarts[container.Name]})
+	}
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		containers = append(containers, Container{Name: status.Name, Started: started[status.Name], Restarts: restarts[status.Name]})
 	}
 
-	return containerNames, nil
+	return containers, pod, nil
 }
 
-// RetrievePodLogs retrieves logs for a specific pod and container
-func RetrievePodLogs(client *kubernetes.Clientset, namespace, podName, containerName string) ([]LogEntry, error) {
-	req := client.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
-		Container: containerName,
-	})
+// RestartCounts returns the restart count of each of the pod's containers,
+// keyed by container name
+func RestartCounts(pod *corev1.Pod) map[string]int32 {
+	counts := map[string]int32{}
+	for _, statuses := range [][]corev1.ContainerStatus{
+		pod.Status.InitContainerStatuses,
+		pod.Status.ContainerStatuses,
+		pod.Status.EphemeralContainerStatuses,
+	} {
+		for _, status := range statuses {
+			counts[status.Name] = status.RestartCount
+		}
+	}
+	return counts
+}
+
+// hasStarted reports whether a container has ever run, counting a container
+// waiting to restart since its previous run still has logs
+func hasStarted(status corev1.ContainerStatus) bool {
+	return status.State.Running != nil ||
+		status.State.Terminated != nil ||
+		status.LastTerminationState.Terminated != nil
+}
+
+// LogOptions controls how container logs are retrieved
+type LogOptions struct {
+	// MaxBytes caps how much of a container's log is read (0 for unlimited)
+	MaxBytes int64
+	// LimitBytes asks the API server to stop sending after this many bytes
+	// (0 for unlimited)
+	LimitBytes int64
+	// InsecureSkipTLSVerifyBackend has the API server skip verifying the
+	// kubelet's serving certificate when proxying the log stream
+	InsecureSkipTLSVerifyBackend bool
+	// Previous reads the log of the container's previous run, as kept after
+	// a restart
+	Previous bool
+}
+
+// RetrievePodLogs retrieves logs for a specific pod and container. A missing pod
+// is reported as a *PodNotFoundError, missing permission to read logs as a
+// *ForbiddenError and other failures as a *LogStreamError. When the context's
+// deadline passes mid-stream, the entries read so far are returned along with
+// the error, followed by a notice that the rest is missing.
+func RetrievePodLogs(ctx context.Context, client kubernetes.Interface, namespace, podName, containerName string, opts LogOptions) ([]LogEntry, error) {
+	podLogOpts := &corev1.PodLogOptions{
+		Container:                    containerName,
+		Timestamps:                   true,
+		InsecureSkipTLSVerifyBackend: opts.InsecureSkipTLSVerifyBackend,
+		Previous:                     opts.Previous,
+	}
+	if opts.LimitBytes > 0 {
+		podLogOpts.LimitBytes = &opts.LimitBytes
+	}
+	req := client.CoreV1().Pods(namespace).GetLogs(podName, podLogOpts)
 
-	podLogs, err := req.Stream(context.TODO())
+	podLogs, err := req.Stream(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error opening log stream: %v", err)
+		if apierrors.IsNotFound(err) {
+			return nil, &PodNotFoundError{Namespace: namespace, Pod: podName, Err: err}
+		}
+		if apierrors.IsForbidden(err) {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "get", Resource: "pods", Subresource: "log"}, err)
+		}
+		return nil, &LogStreamError{Namespace: namespace, Pod: podName, Container: containerName, Err: err}
 	}
 	defer podLogs.Close()
 
-	// Read logs
-	var logs []LogEntry
-	logBytes, err := io.ReadAll(podLogs)
+	// Read logs, reading one byte past the cap to detect truncation
+	var reader io.Reader = podLogs
+	if opts.MaxBytes > 0 {
+		reader = io.LimitReader(podLogs, opts.MaxBytes+1)
+	}
+
+	logBytes, err := io.ReadAll(reader)
 	if err != nil {
-		return nil, fmt.Errorf("error reading logs: %v", err)
+		readErr := &LogStreamError{Namespace: namespace, Pod: podName, Container: containerName, Reading: true, Err: err}
+		// Keep what a timed-out stream delivered before it was abandoned
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, readErr
+		}
+		logs := parseLogLines(namespace, podName, containerName, logBytes)
+		return append(logs, timeoutNotice(namespace, podName, containerName, len(logBytes))), readErr
 	}
 
-	// Parse logs into entries
-	logLines := strings.Split(string(logBytes), "\n")
-	for _, line := range logLines {
+	truncated := opts.MaxBytes > 0 && int64(len(logBytes)) > opts.MaxBytes
+	if truncated {
+		logBytes = logBytes[:opts.MaxBytes]
+	}
+	// The API server stops at LimitBytes without saying so, so a log of
+	// exactly that size was most likely cut
+	limited := !truncated && opts.LimitBytes > 0 && int64(len(logBytes)) >= opts.LimitBytes
+
+	logs := parseLogLines(namespace, podName, containerName, logBytes)
+
+	// Note the truncation so it isn't mistaken for the end of the log
+	if truncated {
+		logs = append(logs, truncationNotice(namespace, podName, containerName, opts.MaxBytes, "--max-log-bytes"))
+	}
+	if limited {
+		logs = append(logs, truncationNotice(namespace, podName, containerName, opts.LimitBytes, "--limit-bytes"))
+	}
+
+	return logs, nil
+}
+
+// parseLogLines parses a container's log into entries, one per non-empty line
+func parseLogLines(namespace, podName, containerName string, logBytes []byte) []LogEntry {
+	var logs []LogEntry
+	for _, line := range strings.Split(string(logBytes), "\n") {
 		if line == "" {
 			continue
 		}
+		timestamp, content := splitTimestamp(line)
 		logs = append(logs, LogEntry{
 			Namespace:  namespace,
 			PodName:    podName,
 			Container:  containerName,
-			LogContent: line,
-			Timestamp:  time.Now().Format(time.RFC3339),
+			LogContent: content,
+			Timestamp:  timestamp,
 		})
 	}
+	return logs
+}
 
-	return logs, nil
+// splitTimestamp separates the RFC3339 timestamp the API server prefixes to each
+// line, falling back to the retrieval time when the prefix is missing
+func splitTimestamp(line string) (string, string) {
+	prefix, content, found := strings.Cut(line, " ")
+	if found {
+		if ts, err := time.Parse(time.RFC3339Nano, prefix); err == nil {
+			return ts.Format(time.RFC3339Nano), content
+		}
+	}
+	return time.Now().Format(time.RFC3339Nano), line
 }
@@ -0,0 +1,317 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	fakerest "k8s.io/client-go/rest/fake"
+)
+
+// logsClientset is a fake clientset whose pods return the given log body,
+// since the fake's own GetLogs always answers "fake logs". With stall set the
+// stream hangs after the body until the request's context is done.
+type logsClientset struct {
+	*fake.Clientset
+	body  string
+	stall bool
+}
+
+func (c *logsClientset) CoreV1() typedcorev1.CoreV1Interface {
+	return &logsCoreV1{CoreV1Interface: c.Clientset.CoreV1(), logs: c}
+}
+
+type logsCoreV1 struct {
+	typedcorev1.CoreV1Interface
+	logs *logsClientset
+}
+
+func (c *logsCoreV1) Pods(namespace string) typedcorev1.PodInterface {
+	return &logsPods{PodInterface: c.CoreV1Interface.Pods(namespace), namespace: namespace, logs: c.logs}
+}
+
+type logsPods struct {
+	typedcorev1.PodInterface
+	namespace string
+	logs      *logsClientset
+}
+
+func (p *logsPods) GetLogs(name string, opts *corev1.PodLogOptions) *rest.Request {
+	client := &fakerest.RESTClient{
+		Client: fakerest.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			var body io.Reader = strings.NewReader(p.logs.body)
+			if p.logs.stall {
+				body = io.MultiReader(body, &stalledReader{done: req.Context().Done(), err: req.Context().Err})
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(body)}, nil
+		}),
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         schema.GroupVersion{Version: "v1"},
+		VersionedAPIPath:     "/api/v1/namespaces/" + p.namespace + "/pods/" + name + "/log",
+	}
+	return client.Request()
+}
+
+// stalledReader blocks until done is closed, then fails with err
+type stalledReader struct {
+	done <-chan struct{}
+	err  func() error
+}
+
+func (r *stalledReader) Read([]byte) (int, error) {
+	<-r.done
+	return 0, r.err()
+}
+
+func testPod(namespace, name string, containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	for _, container := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+	}
+	return pod
+}
+
+func TestListPods(t *testing.T) {
+	tests := []struct {
+		name      string
+		objects   []runtime.Object
+		namespace string
+		want      []string
+	}{
+		{
+			name:      "empty namespace",
+			namespace: "empty",
+			want:      nil,
+		},
+		{
+			name: "only pods in the namespace",
+			objects: []runtime.Object{
+				testPod("prod", "api-1", "api"),
+				testPod("prod", "api-2", "api"),
+				testPod("staging", "api-3", "api"),
+			},
+			namespace: "prod",
+			want:      []string{"api-1", "api-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.objects...)
+			got, err := ListPods(context.Background(), client, tt.namespace, PodListOptions{})
+			if err != nil {
+				t.Fatalf("ListPods() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListPods() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListContainers(t *testing.T) {
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	pod := testPod("prod", "api-1", "api", "sidecar")
+	pod.Labels = map[string]string{"app": "api"}
+	pod.Spec.InitContainers = []corev1.Container{{Name: "migrate"}}
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{Name: "migrate", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}},
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{Name: "api", State: running, RestartCount: 3},
+		{Name: "sidecar", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}}},
+	}
+
+	tests := []struct {
+		name        string
+		pod         string
+		includeInit bool
+		want        []Container
+		wantErr     bool
+	}{
+		{
+			name: "several containers",
+			pod:  "api-1",
+			want: []Container{
+				{Name: "api", Started: true, Restarts: 3},
+				{Name: "sidecar"},
+			},
+		},
+		{
+			name:        "init containers first",
+			pod:         "api-1",
+			includeInit: true,
+			want: []Container{
+				{Name: "migrate", Init: true, Started: true},
+				{Name: "api", Started: true, Restarts: 3},
+				{Name: "sidecar"},
+			},
+		},
+		{
+			name:    "missing pod",
+			pod:     "api-2",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(pod)
+			got, gotPod, err := ListContainers(context.Background(), client, "prod", tt.pod, tt.includeInit)
+			if tt.wantErr {
+				var notFound *PodNotFoundError
+				if !errors.As(err, &notFound) {
+					t.Fatalf("ListContainers() error = %v, want a *PodNotFoundError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListContainers() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListContainers() = %+v, want %+v", got, tt.want)
+			}
+			if gotPod.Labels["app"] != "api" {
+				t.Errorf("ListContainers() pod labels = %v, want app=api", gotPod.Labels)
+			}
+		})
+	}
+}
+
+func TestRetrievePodLogs(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		opts LogOptions
+		want []LogEntry
+	}{
+		{
+			name: "empty log",
+			body: "",
+			want: nil,
+		},
+		{
+			name: "timestamped lines",
+			body: "2024-11-27T10:00:00.123456789Z starting server\n" +
+				"2024-11-27T10:00:01Z ERROR: connection timeout after 30s\n" +
+				"\n" +
+				"2024-11-27T10:00:02.5Z listening on :8080\n",
+			want: []LogEntry{
+				{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:00.123456789Z", LogContent: "starting server"},
+				{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:01Z", LogContent: "ERROR: connection timeout after 30s"},
+				{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:02.5Z", LogContent: "listening on :8080"},
+			},
+		},
+		{
+			name: "truncated at max bytes",
+			body: "2024-11-27T10:00:00Z first\n2024-11-27T10:00:01Z second\n",
+			opts: LogOptions{MaxBytes: 27},
+			want: []LogEntry{
+				{Namespace: "prod", PodName: "api-1", Container: "api", Timestamp: "2024-11-27T10:00:00Z", LogContent: "first"},
+				{Namespace: "prod", PodName: "api-1", Container: "api", LogContent: "[hallucino] log truncated after 27 bytes (--max-log-bytes), later entries are missing"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &logsClientset{Clientset: fake.NewSimpleClientset(testPod("prod", "api-1", "api")), body: tt.body}
+			got, err := RetrievePodLogs(context.Background(), client, "prod", "api-1", "api", tt.opts)
+			if err != nil {
+				t.Fatalf("RetrievePodLogs() error = %v", err)
+			}
+			// Notices are stamped with the retrieval time
+			for i := range got {
+				if IsTruncation(got[i].LogContent) {
+					got[i].Timestamp = ""
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RetrievePodLogs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrievePodLogsTimeout(t *testing.T) {
+	body := "2024-11-27T10:00:00Z first\n2024-11-27T10:00:01Z second\n"
+	client := &logsClientset{Clientset: fake.NewSimpleClientset(testPod("prod", "api-1", "api")), body: body, stall: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	got, err := RetrievePodLogs(ctx, client, "prod", "api-1", "api", LogOptions{})
+
+	var streamErr *LogStreamError
+	if !errors.As(err, &streamErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RetrievePodLogs() error = %v, want a *LogStreamError for the deadline", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("RetrievePodLogs() = %+v, want the two lines read and a notice", got)
+	}
+	if got[0].LogContent != "first" || got[1].LogContent != "second" {
+		t.Errorf("RetrievePodLogs() = %+v, want the lines read before the timeout", got[:2])
+	}
+	want := "[hallucino] log stream timed out after 55 bytes, later entries are missing"
+	if got[2].LogContent != want || !IsTruncation(got[2].LogContent) {
+		t.Errorf("RetrievePodLogs() notice = %q, want %q", got[2].LogContent, want)
+	}
+}
+
+func TestSplitTimestamp(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		wantTime    string
+		wantContent string
+	}{
+		{
+			name:        "nanosecond timestamp",
+			line:        "2024-11-27T10:00:00.123456789Z GET /health 200",
+			wantTime:    "2024-11-27T10:00:00.123456789Z",
+			wantContent: "GET /health 200",
+		},
+		{
+			name:        "offset timestamp",
+			line:        "2024-11-27T10:00:00+01:00 ready",
+			wantTime:    "2024-11-27T10:00:00+01:00",
+			wantContent: "ready",
+		},
+		{
+			name:        "no timestamp",
+			line:        "panic: runtime error",
+			wantContent: "panic: runtime error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTime, gotContent := splitTimestamp(tt.line)
+			if gotContent != tt.wantContent {
+				t.Errorf("splitTimestamp() content = %q, want %q", gotContent, tt.wantContent)
+			}
+			if tt.wantTime != "" && gotTime != tt.wantTime {
+				t.Errorf("splitTimestamp() timestamp = %q, want %q", gotTime, tt.wantTime)
+			}
+			// Lines without a prefix fall back to the retrieval time
+			if _, err := time.Parse(time.RFC3339Nano, gotTime); err != nil {
+				t.Errorf("splitTimestamp() timestamp = %q, not RFC3339: %v", gotTime, err)
+			}
+		})
+	}
+}
+
+var _ kubernetes.Interface = &logsClientset{}
@@ -0,0 +1,63 @@
+package k8s
+
+import (
+	"regexp"
+	"slices"
+)
+
+// DeniedContent replaces the content of entries withheld by a DenyPolicy
+const DeniedContent = "[REDACTED: policy]"
+
+// DenyPolicy withholds logs that must never leave the cluster, stricter than
+// redaction: denied namespaces aren't retrieved at all, and entries matching
+// a pattern are dropped or have their whole content replaced
+type DenyPolicy struct {
+	// Namespaces are never retrieved from
+	Namespaces []string
+	// Patterns deny any entry whose content they match
+	Patterns []*regexp.Regexp
+	// Drop discards denied entries instead of replacing their content with
+	// DeniedContent
+	Drop bool
+}
+
+// DeniesNamespace reports whether logs from the namespace are denied. A nil
+// policy denies nothing.
+func (p *DenyPolicy) DeniesNamespace(namespace string) bool {
+	return p != nil && slices.Contains(p.Namespaces, namespace)
+}
+
+// Apply enforces the policy on entries, filtering the slice in place. Entries
+// from denied namespaces are always dropped, since they shouldn't have been
+// retrieved.
+func (p *DenyPolicy) Apply(logs []LogEntry) []LogEntry {
+	if p == nil {
+		return logs
+	}
+
+	kept := logs[:0]
+	for _, log := range logs {
+		if p.DeniesNamespace(log.Namespace) {
+			continue
+		}
+		if p.denies(log.LogContent) {
+			if p.Drop {
+				continue
+			}
+			log.LogContent = DeniedContent
+		}
+		kept = append(kept, log)
+	}
+	clear(logs[len(kept):])
+	return kept
+}
+
+// denies reports whether any pattern matches the content
+func (p *DenyPolicy) denies(content string) bool {
+	for _, re := range p.Patterns {
+		if re.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// PodNotFoundError reports that a pod doesn't exist, usually because it was
+// deleted after the pods were listed
+type PodNotFoundError struct {
+	Namespace string
+	Pod       string
+	Err       error
+}
+
+func (e *PodNotFoundError) Error() string {
+	return fmt.Sprintf("pod %s/%s not found", e.Namespace, e.Pod)
+}
+
+func (e *PodNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// ContainerNotFoundError reports containers that don't exist in a pod
+type ContainerNotFoundError struct {
+	Namespace  string
+	Pod        string
+	Containers []string
+	Err        error
+}
+
+func (e *ContainerNotFoundError) Error() string {
+	return fmt.Sprintf("container(s) not found: %s", strings.Join(e.Containers, ", "))
+}
+
+func (e *ContainerNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// LogStreamError reports a failure to open or read a container's log stream
+type LogStreamError struct {
+	Namespace string
+	Pod       string
+	Container string
+	// Reading is set when the stream opened but failed part way through
+	Reading bool
+	Err     error
+}
+
+func (e *LogStreamError) Error() string {
+	if e.Reading {
+		return fmt.Sprintf("error reading logs: %v", e.Err)
+	}
+	return fmt.Sprintf("error opening log stream: %v", e.Err)
+}
+
+func (e *LogStreamError) Unwrap() error {
+	return e.Err
+}
+
+// ForbiddenError reports that RBAC denied a request, naming the missing
+// permission and how to grant it
+type ForbiddenError struct {
+	Permission Permission
+	// User is the user or service account that was denied, when the API
+	// server names it, e.g. system:serviceaccount:ops:hallucino
+	User string
+	Err  error
+}
+
+func (e *ForbiddenError) Error() string {
+	user := e.User
+	if user == "" {
+		user = "the current user"
+	}
+	return fmt.Sprintf("forbidden: %s cannot %s, grant it with: %s", user, e.Permission, e.Grant())
+}
+
+func (e *ForbiddenError) Unwrap() error {
+	return e.Err
+}
+
+// Grant returns kubectl commands that create a Role with the missing rule, or
+// a ClusterRole when it isn't limited to a namespace, and bind it to the user
+func (e *ForbiddenError) Grant() string {
+	p := e.Permission
+	name := "hallucino-" + p.Verb + "-" + strings.NewReplacer("/", "-", ".", "-").Replace(p.resource())
+
+	// Service accounts are bound by namespace and name, other users by name
+	subject := "--serviceaccount=<namespace>:<name>"
+	if sa, ok := strings.CutPrefix(e.User, "system:serviceaccount:"); ok {
+		subject = "--serviceaccount=" + sa
+	} else if e.User != "" {
+		subject = "--user=" + e.User
+	}
+
+	if p.Namespace == "" {
+		return fmt.Sprintf("kubectl create clusterrole %s --verb=%s --resource=%s && kubectl create clusterrolebinding %s --clusterrole=%s %s",
+			name, p.Verb, p.resource(), name, name, subject)
+	}
+	return fmt.Sprintf("kubectl create role %s -n %s --verb=%s --resource=%s && kubectl create rolebinding %s -n %s --role=%s %s",
+		name, p.Namespace, p.Verb, p.resource(), name, p.Namespace, name, subject)
+}
+
+// forbiddenUser extracts the denied user from the RBAC authorizer's message,
+// e.g. User "system:serviceaccount:ops:hallucino" cannot get resource ...
+var forbiddenUser = regexp.MustCompile(`User "([^"]+)" cannot`)
+
+// forbidden converts a Forbidden API error into a ForbiddenError for the
+// permission the request needed, returning other errors unchanged
+func forbidden(p Permission, err error) error {
+	if !apierrors.IsForbidden(err) {
+		return err
+	}
+	forbiddenErr := &ForbiddenError{Permission: p, Err: err}
+	if match := forbiddenUser.FindStringSubmatch(err.Error()); match != nil {
+		forbiddenErr.User = match[1]
+	}
+	return forbiddenErr
+}
+
+// podError converts a NotFound API error for a pod into a PodNotFoundError and
+// a Forbidden one into a ForbiddenError, returning other errors unchanged
+func podError(namespace, podName string, err error) error {
+	if apierrors.IsNotFound(err) {
+		return &PodNotFoundError{Namespace: namespace, Pod: podName, Err: err}
+	}
+	return forbidden(Permission{Namespace: namespace, Verb: "get", Resource: "pods"}, err)
+}
@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// containerFieldPath extracts the container name from an event's involved
+// object field path, e.g. spec.containers{api}
+var containerFieldPath = regexp.MustCompile(`^spec\.(?:initContainers|containers|ephemeralContainers)\{(.+)\}$`)
+
+// ListPodEvents retrieves the events recorded for a pod, such as scheduling
+// failures, image pull errors and evictions, as synthetic log entries. Events
+// about a specific container are attributed to it.
+func ListPodEvents(ctx context.Context, client kubernetes.Interface, namespace, podName string) ([]LogEntry, error) {
+	selector := fields.Set{
+		"involvedObject.kind": "Pod",
+		"involvedObject.name": podName,
+	}.AsSelector().String()
+
+	opts := metav1.ListOptions{FieldSelector: selector}
+	var entries []LogEntry
+	for {
+		eventList, err := client.CoreV1().Events(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "list", Resource: "events"}, err)
+		}
+
+		for _, event := range eventList.Items {
+			entry := LogEntry{
+				Namespace:  namespace,
+				PodName:    podName,
+				LogContent: eventMessage(event),
+				Timestamp:  eventTime(event),
+			}
+			if match := containerFieldPath.FindStringSubmatch(event.InvolvedObject.FieldPath); match != nil {
+				entry.Container = match[1]
+			}
+			entries = append(entries, entry)
+		}
+
+		if eventList.Continue == "" {
+			return entries, nil
+		}
+		opts.Continue = eventList.Continue
+	}
+}
+
+// eventMessage describes an event, e.g.
+// "[hallucino] event Warning BackOff: Back-off restarting failed container (x12)"
+func eventMessage(event corev1.Event) string {
+	message := fmt.Sprintf("[hallucino] event %s %s: %s", event.Type, event.Reason, event.Message)
+	if event.Count > 1 {
+		message += fmt.Sprintf(" (x%d)", event.Count)
+	}
+	return message
+}
+
+// eventTime returns when the event last occurred, falling back through the
+// older timestamp fields that not every event source sets
+func eventTime(event corev1.Event) string {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time.Format(time.RFC3339Nano)
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time.Format(time.RFC3339Nano)
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time.Format(time.RFC3339Nano)
+	default:
+		return event.CreationTimestamp.Time.Format(time.RFC3339Nano)
+	}
+}
@@ -0,0 +1,46 @@
+package k8s
+
+import "regexp"
+
+// DefaultContinuationPatterns match lines that continue the previous entry,
+// such as Java and Go stack trace frames
+var DefaultContinuationPatterns = []string{
+	`^\s+`,                        // indented frames
+	`^at\s`,                       // Java frames without indentation
+	`^Caused by:`,                 // Java exception causes
+	`^\.\.\. \d+ more`,            // Java elided frames
+	`^goroutine \d+ \[`,           // Go goroutine headers
+	`^created by `,                // Go goroutine origins
+	`^[\w./-]+\.[\w.()*]+\(.*\)$`, // Go function frames
+}
+
+// GroupMultiline merges continuation lines into the preceding entry so a stack
+// trace becomes a single event. Entries must come from a single container in
+// their original order.
+func GroupMultiline(logs []LogEntry, continuation []*regexp.Regexp) []LogEntry {
+	if len(continuation) == 0 {
+		return logs
+	}
+
+	var grouped []LogEntry
+	for _, log := range logs {
+		if len(grouped) > 0 && isContinuation(log.LogContent, continuation) {
+			last := &grouped[len(grouped)-1]
+			last.LogContent += "\n" + log.LogContent
+			continue
+		}
+		grouped = append(grouped, log)
+	}
+
+	return grouped
+}
+
+// isContinuation reports whether a line matches any continuation pattern
+func isContinuation(line string, continuation []*regexp.Regexp) bool {
+	for _, re := range continuation {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package k8s
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ContainerTerminationInfo reports containers in a pod that terminated
+// abnormally, such as being OOMKilled, as synthetic log entries. The reason and
+// exit code are only recorded in the pod status, so they are otherwise missed
+// when a container dies without logging anything. The pod is the one returned
+// by ListContainers, so its status isn't fetched twice.
+func ContainerTerminationInfo(pod *corev1.Pod) []LogEntry {
+	var entries []LogEntry
+	for _, group := range []struct {
+		statuses []corev1.ContainerStatus
+		init     bool
+	}{
+		{pod.Status.InitContainerStatuses, true},
+		{pod.Status.ContainerStatuses, false},
+		{pod.Status.EphemeralContainerStatuses, false},
+	} {
+		for _, status := range group.statuses {
+			// Report the current termination as well as the one that caused the
+			// last restart
+			for _, terminated := range []*corev1.ContainerStateTerminated{
+				status.State.Terminated,
+				status.LastTerminationState.Terminated,
+			} {
+				if !abnormalTermination(terminated) {
+					continue
+				}
+				entries = append(entries, LogEntry{
+					Namespace:     pod.Namespace,
+					PodName:       pod.Name,
+					Container:     status.Name,
+					LogContent:    terminationMessage(status.Name, terminated),
+					Timestamp:     terminationTime(terminated),
+					InitContainer: group.init,
+				})
+			}
+		}
+	}
+
+	return entries
+}
+
+// abnormalTermination reports whether a container was killed or exited with
+// a failure, ignoring containers that completed successfully
+func abnormalTermination(terminated *corev1.ContainerStateTerminated) bool {
+	if terminated == nil {
+		return false
+	}
+	return terminated.ExitCode != 0 || terminated.Reason == "OOMKilled"
+}
+
+// terminationMessage describes a termination, e.g.
+// "[hallucino] container api terminated: OOMKilled, exit code 137"
+func terminationMessage(container string, terminated *corev1.ContainerStateTerminated) string {
+	reason := terminated.Reason
+	if reason == "" {
+		reason = "Unknown"
+	}
+
+	message := fmt.Sprintf("[hallucino] container %s terminated: %s, exit code %d", container, reason, terminated.ExitCode)
+	if terminated.Signal != 0 {
+		message += fmt.Sprintf(", signal %d", terminated.Signal)
+	}
+	if terminated.Message != "" {
+		message += ": " + terminated.Message
+	}
+	return message
+}
+
+// terminationTime returns when the container finished, falling back to the
+// retrieval time when the kubelet didn't record it
+func terminationTime(terminated *corev1.ContainerStateTerminated) string {
+	if terminated.FinishedAt.IsZero() {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	return terminated.FinishedAt.Time.Format(time.RFC3339Nano)
+}
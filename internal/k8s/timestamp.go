@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timestamp format presets accepted by FormatTimestamp alongside Go layouts
+const (
+	TimestampRelative = "relative"
+	TimestampTimeOnly = "time-only"
+	TimestampNone     = "none"
+)
+
+// FormatTimestamp renders an RFC3339 entry timestamp using a preset or a Go
+// time layout. An empty format, or a timestamp that doesn't parse, is returned
+// unchanged.
+func FormatTimestamp(timestamp, format string) string {
+	if format == TimestampNone {
+		return ""
+	}
+	if format == "" {
+		return timestamp
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return timestamp
+	}
+
+	switch format {
+	case TimestampRelative:
+		return relativeTime(time.Since(t))
+	case TimestampTimeOnly:
+		return t.Format("15:04:05")
+	default:
+		return t.Format(format)
+	}
+}
+
+// relativeTime describes an age in its largest whole unit, e.g. "3m ago"
+func relativeTime(age time.Duration) string {
+	// Clock skew can put entries slightly in the future
+	age = max(age, 0)
+
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds ago", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+}
@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// truncationMarkers match lines showing that log data is missing: notices
+// added by RetrievePodLogs, the kubelet's error when a log file is rotated or
+// corrupted mid-read, and the markers runtimes and log shippers such as
+// fluentd and fluent-bit insert when they cut lines or rotate files
+var truncationMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`^\[hallucino\] log truncated `),
+	regexp.MustCompile(`^\[hallucino\] log stream timed out `),
+	regexp.MustCompile(`unexpected stream type`),
+	regexp.MustCompile(`(?i)\[truncated\]|\(truncated\)|\.\.\.\s*truncated\b`),
+	regexp.MustCompile(`(?i)\blog (line|message|entry) (was |has been )?truncated\b`),
+	regexp.MustCompile(`(?i)\blogs? (file )?(was |has been )?rotated\b`),
+}
+
+// IsTruncation reports whether an entry's content marks missing log data, so
+// the capture around it is incomplete
+func IsTruncation(content string) bool {
+	for _, re := range truncationMarkers {
+		if re.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncationNotice is the entry added after a container's log when a byte
+// limit cut it short, so it isn't mistaken for the end of the log
+func truncationNotice(namespace, podName, containerName string, limit int64, flag string) LogEntry {
+	return LogEntry{
+		Namespace:  namespace,
+		PodName:    podName,
+		Container:  containerName,
+		LogContent: fmt.Sprintf("[hallucino] log truncated after %d bytes (%s), later entries are missing", limit, flag),
+		Timestamp:  time.Now().Format(time.RFC3339Nano),
+	}
+}
+
+// timeoutNotice is the entry added after the part of a container's log read
+// before its stream timed out
+func timeoutNotice(namespace, podName, containerName string, read int) LogEntry {
+	return LogEntry{
+		Namespace:  namespace,
+		PodName:    podName,
+		Container:  containerName,
+		LogContent: fmt.Sprintf("[hallucino] log stream timed out after %d bytes, later entries are missing", read),
+		Timestamp:  time.Now().Format(time.RFC3339Nano),
+	}
+}
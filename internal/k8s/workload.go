@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Workload kinds that can be resolved to their managed pods
+const (
+	KindDeployment  = "deployment"
+	KindStatefulSet = "statefulset"
+	KindDaemonSet   = "daemonset"
+	KindJob         = "job"
+	KindCronJob     = "cronjob"
+)
+
+// PodsForWorkload retrieves the names of the pods managed by a workload by
+// reading its label selector, or for Jobs and CronJobs their owner references
+func PodsForWorkload(ctx context.Context, client kubernetes.Interface, namespace, kind, name string, opts PodListOptions) ([]string, error) {
+	var selector *metav1.LabelSelector
+	switch kind {
+	case KindJob, KindCronJob:
+		return PodsForJob(ctx, client, namespace, kind, name, opts)
+	case KindDeployment:
+		deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "get", Group: "apps", Resource: "deployments"}, err)
+		}
+		selector = deployment.Spec.Selector
+	case KindStatefulSet:
+		statefulSet, err := client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "get", Group: "apps", Resource: "statefulsets"}, err)
+		}
+		selector = statefulSet.Spec.Selector
+	case KindDaemonSet:
+		daemonSet, err := client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "get", Group: "apps", Resource: "daemonsets"}, err)
+		}
+		selector = daemonSet.Spec.Selector
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %q", kind)
+	}
+
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on %s/%s: %v", kind, name, err)
+	}
+
+	return listPodNames(ctx, client, namespace, metav1.ListOptions{
+		LabelSelector: labelSelector.String(),
+		FieldSelector: opts.FieldSelector,
+		Limit:         opts.PageSize,
+	})
+}
+
+// PodsForJob retrieves the names of the pods owned by a Job, or by any of the
+// Jobs a CronJob spawned, when kind is KindCronJob. Pods are matched by owner
+// reference, so completed and failed pods that are kept around are included.
+func PodsForJob(ctx context.Context, client kubernetes.Interface, namespace, kind, name string, opts PodListOptions) ([]string, error) {
+	jobs := map[types.UID]bool{}
+	switch kind {
+	case KindJob:
+		job, err := client.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "get", Group: "batch", Resource: "jobs"}, err)
+		}
+		jobs[job.UID] = true
+	case KindCronJob:
+		cronJob, err := client.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "get", Group: "batch", Resource: "cronjobs"}, err)
+		}
+		jobList, err := client.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "list", Group: "batch", Resource: "jobs"}, err)
+		}
+		for _, job := range jobList.Items {
+			if ownedBy(job.OwnerReferences, cronJob.UID) {
+				jobs[job.UID] = true
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported job kind %q", kind)
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	// Page through the namespace's pods, keeping those the jobs own
+	var podNames []string
+	listOpts := metav1.ListOptions{FieldSelector: opts.FieldSelector, Limit: opts.PageSize}
+	for {
+		podList, err := client.CoreV1().Pods(namespace).List(ctx, listOpts)
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "list", Resource: "pods"}, err)
+		}
+		for _, pod := range podList.Items {
+			for _, owner := range pod.OwnerReferences {
+				if jobs[owner.UID] {
+					podNames = append(podNames, pod.Name)
+					break
+				}
+			}
+		}
+		if podList.Continue == "" {
+			return podNames, nil
+		}
+		listOpts.Continue = podList.Continue
+	}
+}
+
+// ownedBy reports whether the owner references include uid
+func ownedBy(owners []metav1.OwnerReference, uid types.UID) bool {
+	for _, owner := range owners {
+		if owner.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// RevisionAnnotation records the Deployment revision a ReplicaSet was created
+// for
+const RevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// PodRevisions maps the names of a Deployment's pods to the revision of the
+// ReplicaSet that owns them, read from its RevisionAnnotation. Pods of
+// ReplicaSets the Deployment doesn't own are left out.
+func PodRevisions(ctx context.Context, client kubernetes.Interface, namespace, name string, opts PodListOptions) (map[string]string, error) {
+	deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, forbidden(Permission{Namespace: namespace, Verb: "get", Group: "apps", Resource: "deployments"}, err)
+	}
+	labelSelector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on %s/%s: %v", KindDeployment, name, err)
+	}
+
+	replicaSets, err := client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector.String()})
+	if err != nil {
+		return nil, forbidden(Permission{Namespace: namespace, Verb: "list", Group: "apps", Resource: "replicasets"}, err)
+	}
+	revisions := map[types.UID]string{}
+	for _, replicaSet := range replicaSets.Items {
+		if revision := replicaSet.Annotations[RevisionAnnotation]; revision != "" && ownedBy(replicaSet.OwnerReferences, deployment.UID) {
+			revisions[replicaSet.UID] = revision
+		}
+	}
+
+	// Page through the Deployment's pods, matching them to a ReplicaSet
+	podRevisions := map[string]string{}
+	listOpts := metav1.ListOptions{LabelSelector: labelSelector.String(), FieldSelector: opts.FieldSelector, Limit: opts.PageSize}
+	for {
+		podList, err := client.CoreV1().Pods(namespace).List(ctx, listOpts)
+		if err != nil {
+			return nil, forbidden(Permission{Namespace: namespace, Verb: "list", Resource: "pods"}, err)
+		}
+		for _, pod := range podList.Items {
+			for _, owner := range pod.OwnerReferences {
+				if revision, ok := revisions[owner.UID]; ok {
+					podRevisions[pod.Name] = revision
+					break
+				}
+			}
+		}
+		if podList.Continue == "" {
+			return podRevisions, nil
+		}
+		listOpts.Continue = podList.Continue
+	}
+}
@@ -1,15 +1,32 @@
 package logger
 
 import (
+	"fmt"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func NewLogger() (*zap.Logger, error) {
+// NewLogger builds a leveled logger writing human-readable diagnostics to
+// stderr, keeping stdout free for results
+func NewLogger(level string) (*zap.Logger, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
 	config := zap.NewProductionConfig()
+	config.Level = zap.NewAtomicLevelAt(lvl)
+	config.Encoding = "console"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	config.EncoderConfig.TimeKey = "timestamp"
-	config.OutputPaths = []string{"stdout"}
+	config.OutputPaths = []string{"stderr"}
+	config.Sampling = nil
+
+	// Caller and stack details only help when debugging
+	config.DisableCaller = lvl > zapcore.DebugLevel
+	config.DisableStacktrace = lvl > zapcore.DebugLevel
 
 	return config.Build()
 }
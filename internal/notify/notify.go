@@ -0,0 +1,17 @@
+// Package notify posts analysis results to chat and alerting services
+package notify
+
+import "context"
+
+// Message is an analysis result to deliver
+type Message struct {
+	// Title summarizes the findings in one line
+	Title string
+	// Markdown is the insights or local report, as rendered in the terminal
+	Markdown string
+}
+
+// Notifier delivers messages to a single destination, e.g. a Slack channel
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
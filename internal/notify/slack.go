@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxSlackText keeps messages below Slack's 40,000 character limit on text
+const maxSlackText = 39000
+
+// SlackNotifier posts messages to a Slack incoming webhook
+type SlackNotifier struct {
+	WebhookURL string
+	// Client sends the requests, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// Notify posts the message, with its Markdown converted to Slack mrkdwn
+func (s *SlackNotifier) Notify(ctx context.Context, msg Message) error {
+	text := ToMrkdwn(msg.Markdown)
+	if msg.Title != "" {
+		text = "*" + msg.Title + "*\n\n" + text
+	}
+	if len(text) > maxSlackText {
+		text = strings.ToValidUTF8(text[:maxSlackText], "") + "\n\n_(truncated)_"
+	}
+
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Webhooks answer "ok", or a short reason such as "invalid_token"
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Slack responded %s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+	return nil
+}
+
+var (
+	mdHeading = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[*+-]\s+`)
+	mdItalic  = regexp.MustCompile(`(^|[^*])\*([^*\s](?:[^*]*[^*\s])?)\*([^*]|$)`)
+	mdBold    = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	mdLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+
+	// mrkdwnEscaper escapes the characters Slack treats as control sequences,
+	// so log content such as <nil> or a && b shows as written
+	mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// ToMrkdwn converts the Markdown the analysis produces to Slack's mrkdwn:
+// headings and bold become *bold*, *italic* becomes _italic_, list items get
+// bullets and links become <url|text>. Code blocks are left as they are. &, <
+// and > are escaped first, so only the links added here are read as markup.
+func ToMrkdwn(markdown string) string {
+	lines := strings.Split(mrkdwnEscaper.Replace(markdown), "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+
+		if match := mdHeading.FindStringSubmatch(line); match != nil {
+			// Headings often wrap their text in bold already
+			lines[i] = "*" + strings.Trim(match[1], "*_") + "*"
+			continue
+		}
+		line = mdBullet.ReplaceAllString(line, "$1• ")
+		line = mdItalic.ReplaceAllString(line, "${1}_${2}_${3}")
+		line = mdBold.ReplaceAllString(line, "*$2*")
+		line = mdLink.ReplaceAllString(line, "<$2|$1>")
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,40 @@
+package notify
+
+import "testing"
+
+func TestToMrkdwn(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "heading and bold",
+			markdown: "## **Root Cause**\n- **api-1** ran out of memory",
+			want:     "*Root Cause*\n• *api-1* ran out of memory",
+		},
+		{
+			name:     "link",
+			markdown: "See [the runbook](https://example.com/runbook?a=1&b=2)",
+			want:     "See <https://example.com/runbook?a=1&amp;b=2|the runbook>",
+		},
+		{
+			name:     "control characters",
+			markdown: "- handler returned <nil> when retries > 3 && cache cold",
+			want:     "• handler returned &lt;nil&gt; when retries &gt; 3 &amp;&amp; cache cold",
+		},
+		{
+			name:     "control characters in code",
+			markdown: "```\nif err != nil && <-done {\n```",
+			want:     "```\nif err != nil &amp;&amp; &lt;-done {\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToMrkdwn(tt.markdown); got != tt.want {
+				t.Errorf("ToMrkdwn(%q) = %q, want %q", tt.markdown, got, tt.want)
+			}
+		})
+	}
+}
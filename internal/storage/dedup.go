@@ -0,0 +1,76 @@
+package storage
+
+import "fmt"
+
+// DeduplicateGlobal removes every repeat of an entry's content within the same
+// container, wherever it occurs, keeping the first occurrence with the total
+// count appended, e.g. "connection refused [x12]". Unlike sorting, this changes
+// what the log order shows: a line that recurs throughout the capture appears
+// only at its first timestamp, so later bursts no longer show up in place. It
+// returns the number of entries removed.
+func (ls *LogStorage) DeduplicateGlobal() int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	type contentKey struct {
+		namespace, pod, container, content string
+	}
+
+	// Count every content first so the kept entry can carry the total
+	ls.unwind()
+	counts := map[contentKey]int{}
+	for _, log := range ls.logs {
+		counts[contentKey{log.Namespace, log.PodName, log.Container, log.LogContent}]++
+	}
+
+	seen := make(map[contentKey]bool, len(counts))
+	kept := ls.logs[:0]
+	for _, log := range ls.logs {
+		key := contentKey{log.Namespace, log.PodName, log.Container, log.LogContent}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if n := counts[key]; n > 1 {
+			log.LogContent = fmt.Sprintf("%s [x%d]", log.LogContent, n)
+		}
+		kept = append(kept, log)
+	}
+
+	removed := len(ls.logs) - len(kept)
+	// Clear the tail so removed entries can be collected
+	clear(ls.logs[len(kept):])
+	ls.logs = kept
+	return removed
+}
+
+// DeduplicateOverlap removes entries identical to an earlier one in the same
+// container, server timestamp included, as produced when a reconnect or the
+// previous instance's log re-reads a window that was already retrieved. The
+// kubelet timestamps lines to the nanosecond, so genuine repeats of a line
+// differ in time and are kept. It returns the number of entries removed.
+func (ls *LogStorage) DeduplicateOverlap() int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	type entryKey struct {
+		namespace, pod, container, timestamp, content string
+	}
+
+	ls.unwind()
+	seen := make(map[entryKey]bool, len(ls.logs))
+	kept := ls.logs[:0]
+	for _, log := range ls.logs {
+		key := entryKey{log.Namespace, log.PodName, log.Container, log.Timestamp, log.LogContent}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, log)
+	}
+
+	removed := len(ls.logs) - len(kept)
+	clear(ls.logs[len(kept):])
+	ls.logs = kept
+	return removed
+}
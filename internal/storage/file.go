@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hallucino/internal/k8s"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzipMagic are the leading bytes of every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// SaveToFile writes the stored logs to path as newline-delimited JSON,
+// gzip-compressed when the path ends in ".gz"
+func (ls *LogStorage) SaveToFile(path string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("failed to write %s: %w", path, cerr)
+		}
+	}()
+
+	var w io.Writer = f
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(f)
+		defer func() {
+			// Flush the compressed stream before the file is closed
+			if cerr := gz.Close(); err == nil && cerr != nil {
+				err = fmt.Errorf("failed to write %s: %w", path, cerr)
+			}
+		}()
+		w = gz
+	}
+
+	if err := ls.WriteNDJSON(w); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadFromFile reads logs written by SaveToFile. Gzip compression is detected
+// from the content rather than the file name, so misnamed files still load.
+func LoadFromFile(path string) (*LogStorage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && string(magic) == string(gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	ls := NewLogStorage()
+	dec := json.NewDecoder(r)
+	for {
+		var log k8s.LogEntry
+		if err := dec.Decode(&log); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		ls.AddLog(log)
+	}
+	return ls, nil
+}
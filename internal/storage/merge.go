@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"container/heap"
+	"hallucino/internal/k8s"
+	"sort"
+	"time"
+)
+
+// MergeStreams merges per-container slices that are each already in time
+// order into a single chronological slice, in O(n log k) for n entries across
+// k streams. Entries with equal timestamps keep the order of their streams.
+func MergeStreams(streams [][]k8s.LogEntry) []k8s.LogEntry {
+	total := 0
+	h := &streamHeap{}
+	for i, stream := range streams {
+		total += len(stream)
+		if len(stream) > 0 {
+			h.cursors = append(h.cursors, &streamCursor{index: i, logs: stream, next: parseTimestamp(stream[0])})
+		}
+	}
+	heap.Init(h)
+
+	merged := make([]k8s.LogEntry, 0, total)
+	for h.Len() > 0 {
+		cursor := h.cursors[0]
+		merged = append(merged, cursor.logs[cursor.pos])
+		cursor.pos++
+		if cursor.pos == len(cursor.logs) {
+			heap.Pop(h)
+			continue
+		}
+		cursor.next = parseTimestamp(cursor.logs[cursor.pos])
+		heap.Fix(h, 0)
+	}
+	return merged
+}
+
+// MergeByTimestamp orders the stored logs chronologically by merging each
+// container's entries, which are retrieved in order, rather than sorting them
+// all. Streams that are out of order, e.g. because of synthetic entries, are
+// sorted first.
+func (ls *LogStorage) MergeByTimestamp() {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	// Split into streams, keeping the order streams were first seen
+	index := map[string]int{}
+	var streams [][]k8s.LogEntry
+	ls.unwind()
+	for _, log := range ls.logs {
+		key := log.Namespace + "/" + log.PodName + "/" + log.Container
+		i, ok := index[key]
+		if !ok {
+			i = len(streams)
+			index[key] = i
+			streams = append(streams, nil)
+		}
+		streams[i] = append(streams[i], log)
+	}
+
+	for _, stream := range streams {
+		times := make([]time.Time, len(stream))
+		for i, log := range stream {
+			times[i] = parseTimestamp(log)
+		}
+		if !sort.SliceIsSorted(times, func(i, j int) bool { return times[i].Before(times[j]) }) {
+			sort.Stable(byTimestamp{logs: stream, times: times})
+		}
+	}
+
+	ls.logs = MergeStreams(streams)
+}
+
+// parseTimestamp returns the entry's time, or the zero time when unparseable
+func parseTimestamp(log k8s.LogEntry) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, log.Timestamp)
+	return t
+}
+
+// streamCursor is the read position in one stream being merged
+type streamCursor struct {
+	index int
+	logs  []k8s.LogEntry
+	pos   int
+	next  time.Time
+}
+
+// streamHeap orders cursors by their next entry's time, then by stream
+type streamHeap struct {
+	cursors []*streamCursor
+}
+
+func (h *streamHeap) Len() int { return len(h.cursors) }
+func (h *streamHeap) Less(i, j int) bool {
+	a, b := h.cursors[i], h.cursors[j]
+	if !a.next.Equal(b.next) {
+		return a.next.Before(b.next)
+	}
+	return a.index < b.index
+}
+func (h *streamHeap) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+func (h *streamHeap) Push(x any)    { h.cursors = append(h.cursors, x.(*streamCursor)) }
+func (h *streamHeap) Pop() any {
+	last := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return last
+}
@@ -1,9 +1,19 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"hallucino/internal/k8s"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/fatih/color"
 )
@@ -11,48 +21,458 @@ import (
 type LogStorage struct {
 	logs []k8s.LogEntry
 	mu   sync.RWMutex
+
+	// capacity bounds logs as a ring buffer starting at head (0 for unbounded)
+	capacity int
+	head     int
+	dropped  int
+
+	// timestampFormat controls how pretty printing renders timestamps
+	timestampFormat string
+	// labelColumns are the pod labels pretty printing shows as columns
+	labelColumns []string
+	// template replaces the built-in pretty printing format when set
+	template *template.Template
+	// severity picks the color of each entry's content when pretty printing
+	severity func(k8s.LogEntry) Severity
 }
 
+// Severity is how pretty printing colors an entry's content
+type Severity int
+
+const (
+	SeverityNone Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
 func NewLogStorage() *LogStorage {
 	return &LogStorage{
 		logs: []k8s.LogEntry{},
 	}
 }
 
+// NewLogStorageWithCapacity creates storage that keeps at most n entries,
+// dropping the oldest once full. A capacity of 0 means unbounded.
+func NewLogStorageWithCapacity(n int) *LogStorage {
+	ls := NewLogStorage()
+	ls.capacity = n
+	return ls
+}
+
+// Dropped returns how many entries were discarded to stay within capacity
+func (ls *LogStorage) Dropped() int {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	return ls.dropped
+}
+
+// entries returns the stored logs oldest first. A full ring buffer is copied,
+// since later additions overwrite it in place.
+func (ls *LogStorage) entries() []k8s.LogEntry {
+	if ls.capacity == 0 || len(ls.logs) < ls.capacity {
+		return ls.logs
+	}
+	ordered := make([]k8s.LogEntry, 0, len(ls.logs))
+	ordered = append(ordered, ls.logs[ls.head:]...)
+	return append(ordered, ls.logs[:ls.head]...)
+}
+
+// unwind lays the ring buffer out oldest first so it can be reordered in place
+func (ls *LogStorage) unwind() {
+	if ls.head != 0 {
+		ls.logs = ls.entries()
+		ls.head = 0
+	}
+}
+
+// SetTimestampFormat sets the preset or Go layout used to print timestamps, see
+// k8s.FormatTimestamp
+func (ls *LogStorage) SetTimestampFormat(format string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.timestampFormat = format
+}
+
+// SetLabelColumns sets the pod labels shown as extra columns by pretty printing,
+// taken from each entry's Labels
+func (ls *LogStorage) SetLabelColumns(labels []string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.labelColumns = labels
+}
+
+// SetSeverityFunc sets how pretty printing judges each entry's severity,
+// coloring errors red and warnings yellow. Content isn't colored when unset.
+func (ls *LogStorage) SetSeverityFunc(severity func(k8s.LogEntry) Severity) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.severity = severity
+}
+
+// printOptions returns the settings used by printEntries
+func (ls *LogStorage) printOptions() printOptions {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	return printOptions{timestampFormat: ls.timestampFormat, labelColumns: ls.labelColumns, template: ls.template, severity: ls.severity}
+}
+
 func (ls *LogStorage) AddLog(log k8s.LogEntry) {
 	ls.mu.Lock()
 	defer ls.mu.Unlock()
+
+	// Overwrite the oldest entry once the ring buffer is full
+	if ls.capacity > 0 && len(ls.logs) == ls.capacity {
+		ls.logs[ls.head] = log
+		ls.head = (ls.head + 1) % ls.capacity
+		ls.dropped++
+		return
+	}
 	ls.logs = append(ls.logs, log)
 }
 
 func (ls *LogStorage) GetLogs() []k8s.LogEntry {
 	ls.mu.RLock()
 	defer ls.mu.RUnlock()
-	return ls.logs
+	return ls.entries()
 }
 
-func (ls *LogStorage) PrettyPrintLogs() {
+// Stats returns the number of stored entries and the total size of their content
+func (ls *LogStorage) Stats() (entries int, bytes int) {
 	ls.mu.RLock()
 	defer ls.mu.RUnlock()
 
-	// Use different colors for different elements
-	podColor := color.New(color.FgBlue).SprintFunc()
-	containerColor := color.New(color.FgMagenta).SprintFunc()
-	timestampColor := color.New(color.FgGreen).SprintFunc()
+	for _, log := range ls.entries() {
+		bytes += len(log.LogContent)
+	}
+	return len(ls.logs), bytes
+}
+
+// SortByTimestamp orders the stored logs chronologically. Entries with equal or
+// unparseable timestamps keep their relative order.
+func (ls *LogStorage) SortByTimestamp() {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	// Unparseable timestamps sort as the zero time
+	ls.unwind()
+	times := make([]time.Time, len(ls.logs))
+	for i, log := range ls.logs {
+		times[i], _ = time.Parse(time.RFC3339Nano, log.Timestamp)
+	}
+
+	sort.Stable(byTimestamp{logs: ls.logs, times: times})
+}
+
+// byTimestamp sorts log entries alongside their parsed timestamps
+type byTimestamp struct {
+	logs  []k8s.LogEntry
+	times []time.Time
+}
+
+func (b byTimestamp) Len() int           { return len(b.logs) }
+func (b byTimestamp) Less(i, j int) bool { return b.times[i].Before(b.times[j]) }
+func (b byTimestamp) Swap(i, j int) {
+	b.logs[i], b.logs[j] = b.logs[j], b.logs[i]
+	b.times[i], b.times[j] = b.times[j], b.times[i]
+}
+
+func (ls *LogStorage) PrettyPrintLogs(w io.Writer) {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	printEntries(w, ls.entries(), nil, printOptions{timestampFormat: ls.timestampFormat, labelColumns: ls.labelColumns, template: ls.template})
+}
+
+// WriteText writes the stored logs as PrettyPrintLogs does, but never in
+// color, for saving to files
+func (ls *LogStorage) WriteText(w io.Writer) {
+	// Read the options before locking, since read locks mustn't be nested
+	opts := ls.printOptions()
+	opts.plain = true
+
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	printEntries(w, ls.entries(), nil, opts)
+}
+
+// Search returns the stored entries whose content matches the regular expression
+func (ls *LogStorage) Search(pattern string) ([]k8s.LogEntry, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern %q: %w", pattern, err)
+	}
+
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	var matches []k8s.LogEntry
+	for _, log := range ls.entries() {
+		if re.MatchString(log.LogContent) {
+			matches = append(matches, log)
+		}
+	}
+
+	return matches, nil
+}
+
+// Filter keeps only the entries for which keep returns true and returns the
+// number removed. Markers of missing log data, see k8s.IsTruncation, are always
+// kept so the capture is still reported as incomplete.
+func (ls *LogStorage) Filter(keep func(k8s.LogEntry) bool) int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
 
+	ls.unwind()
+	kept := ls.logs[:0]
 	for _, log := range ls.logs {
-		// Format log entry
-		fmt.Printf("%s | %s | %s | %s\n",
-			timestampColor(log.Timestamp),
-			podColor(log.PodName),
-			containerColor(log.Container),
-			log.LogContent,
-		)
+		if k8s.IsTruncation(log.LogContent) || keep(log) {
+			kept = append(kept, log)
+		}
+	}
+
+	removed := len(ls.logs) - len(kept)
+	clear(ls.logs[len(kept):])
+	ls.logs = kept
+	return removed
+}
+
+// PrettyPrintMatches prints the entries matching the regular expression with
+// each match highlighted
+func (ls *LogStorage) PrettyPrintMatches(w io.Writer, pattern string) error {
+	matches, err := ls.Search(pattern)
+	if err != nil {
+		return err
+	}
+
+	printEntries(w, matches, regexp.MustCompile(pattern), ls.printOptions())
+	return nil
+}
+
+// printOptions controls how printEntries lays out each entry
+type printOptions struct {
+	timestampFormat string
+	labelColumns    []string
+	// template formats each entry instead of the built-in columns
+	template *template.Template
+	// plain disables color, as for files, whatever the color mode
+	plain bool
+	// severity colors content when set
+	severity func(k8s.LogEntry) Severity
+}
+
+// printEntries prints log entries with colored metadata and content colored by
+// severity, highlighting any substrings matched by highlight
+func printEntries(w io.Writer, logs []k8s.LogEntry, highlight *regexp.Regexp, opts printOptions) {
+	// Use different colors for different elements
+	newColor := func(attrs ...color.Attribute) *color.Color {
+		c := color.New(attrs...)
+		if opts.plain {
+			c.DisableColor()
+		}
+		return c
+	}
+	podColor := newColor(color.FgBlue).SprintFunc()
+	containerColor := newColor(color.FgMagenta).SprintFunc()
+	timestampColor := newColor(color.FgGreen).SprintFunc()
+	labelColor := newColor(color.FgCyan).SprintFunc()
+	sourceColor := newColor(color.FgHiBlack).SprintFunc()
+	matchColor := newColor(color.FgBlack, color.BgYellow).SprintFunc()
+	severityColors := map[Severity]*color.Color{
+		SeverityError:   newColor(color.FgRed),
+		SeverityWarning: newColor(color.FgYellow),
+	}
+
+	for _, log := range logs {
+		if opts.template != nil {
+			fmt.Fprint(w, templateLine(opts.template, log))
+			continue
+		}
+
+		// Color the text around any matches by severity, so the highlight
+		// doesn't reset it part way through the line
+		contentColor := fmt.Sprint
+		if opts.severity != nil {
+			if c := severityColors[opts.severity(log)]; c != nil {
+				contentColor = c.Sprint
+			}
+		}
+		content := contentColor(log.LogContent)
+		if highlight != nil {
+			content = highlightMatches(log.LogContent, highlight, matchColor, contentColor)
+		}
+
+		// Format log entry, dropping the timestamp column when disabled and
+		// showing "-" for labels the pod doesn't have
+		var columns []string
+		if opts.timestampFormat != k8s.TimestampNone {
+			columns = append(columns, timestampColor(k8s.FormatTimestamp(log.Timestamp, opts.timestampFormat)))
+		}
+		if log.Source != "" {
+			columns = append(columns, sourceColor(log.Source))
+		}
+		columns = append(columns, podColor(log.PodName), containerColor(log.Container))
+		for _, label := range opts.labelColumns {
+			value, ok := log.Labels[label]
+			if !ok {
+				value = "-"
+			}
+			columns = append(columns, labelColor(value))
+		}
+		fmt.Fprintln(w, strings.Join(append(columns, content), " | "))
+	}
+}
+
+// highlightMatches colors the matches of re in text with match and the text
+// between them with rest
+func highlightMatches(text string, re *regexp.Regexp, match, rest func(...any) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		if loc[0] > last {
+			b.WriteString(rest(text[last:loc[0]]))
+		}
+		if loc[1] > loc[0] {
+			b.WriteString(match(text[loc[0]:loc[1]]))
+		}
+		last = loc[1]
+	}
+	if last < len(text) {
+		b.WriteString(rest(text[last:]))
+	}
+	return b.String()
+}
+
+// WriteCSV writes the stored logs as CSV with a header row
+func (ls *LogStorage) WriteCSV(w io.Writer) error {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"timestamp", "namespace", "pod", "container", "content"}); err != nil {
+		return err
+	}
+	for _, log := range ls.entries() {
+		if err := cw.Write([]string{log.Timestamp, log.Namespace, log.PodName, log.Container, log.LogContent}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// WriteJSON writes the stored logs as a JSON array, or as an object of arrays
+// keyed by group when groupBy names a dimension. The JSON is compact on a
+// single line unless pretty is set, when it's indented for reading.
+func (ls *LogStorage) WriteJSON(w io.Writer, groupBy string, pretty bool) error {
+	enc := json.NewEncoder(w)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+
+	if groupBy != "" {
+		return enc.Encode(ls.GroupBy(groupBy))
+	}
+
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	return enc.Encode(ls.entries())
+}
+
+// WriteNDJSON writes the stored logs as newline-delimited JSON, one entry per
+// line, in the format read by LoadFromFile
+func (ls *LogStorage) WriteNDJSON(w io.Writer) error {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	enc := json.NewEncoder(w)
+	for _, log := range ls.entries() {
+		if err := enc.Encode(log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dimensions accepted by GroupBy
+const (
+	GroupByNamespace = "namespace"
+	GroupByPod       = "pod"
+	GroupByContainer = "container"
+)
+
+// GroupByDimensions lists the dimensions accepted by GroupBy
+var GroupByDimensions = []string{GroupByNamespace, GroupByPod, GroupByContainer}
+
+// GroupBy groups the stored logs by namespace, pod ("namespace/pod") or
+// container ("namespace/pod/container"), keeping the stored order within groups
+func (ls *LogStorage) GroupBy(dim string) map[string][]k8s.LogEntry {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	groups := map[string][]k8s.LogEntry{}
+	for _, log := range ls.entries() {
+		key := groupKey(log, dim)
+		groups[key] = append(groups[key], log)
+	}
+	return groups
+}
+
+// groupKey returns the group a log entry belongs to for a dimension
+func groupKey(log k8s.LogEntry, dim string) string {
+	switch dim {
+	case GroupByNamespace:
+		return log.Namespace
+	case GroupByPod:
+		return log.Namespace + "/" + log.PodName
+	case GroupByContainer:
+		return log.Namespace + "/" + log.PodName + "/" + log.Container
+	default:
+		return ""
+	}
+}
+
+// PrettyPrintGrouped prints the stored logs under a header for each group
+func (ls *LogStorage) PrettyPrintGrouped(w io.Writer, dim string) {
+	groups := ls.GroupBy(dim)
+
+	opts := ls.printOptions()
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	headerColor := color.New(color.Bold, color.FgCyan).SprintFunc()
+	for i, key := range keys {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n", headerColor(fmt.Sprintf("== %s: %s (%d entries) ==", dim, key, len(groups[key]))))
+		printEntries(w, groups[key], nil, opts)
+	}
+}
+
+// Fingerprint returns a hash of the stored entries, changing whenever an entry
+// is added, removed or altered
+func (ls *LogStorage) Fingerprint() string {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+
+	h := sha256.New()
+	for _, log := range ls.entries() {
+		// Separate fields with NUL so adjacent values can't run together
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00", log.Timestamp, log.Namespace, log.PodName, log.Container, log.LogContent)
 	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 func (ls *LogStorage) Clear() {
 	ls.mu.Lock()
 	defer ls.mu.Unlock()
 	ls.logs = []k8s.LogEntry{}
+	ls.head = 0
+	ls.dropped = 0
 }
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"hallucino/internal/k8s"
+	"reflect"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "plain", content: "server started"},
+		{name: "commas", content: "user=42, action=login, result=ok"},
+		{name: "quotes", content: `msg="connection refused" host="db"`},
+		{name: "commas and quotes", content: `{"level":"error","msg":"timeout, retrying \"soon\""}`},
+		{name: "newline", content: "panic: boom\n\tat main.go:12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := k8s.LogEntry{
+				Namespace:  "prod",
+				PodName:    "api-1",
+				Container:  "api",
+				Timestamp:  "2024-11-27T10:00:00Z",
+				LogContent: tt.content,
+			}
+			ls := NewLogStorage()
+			ls.AddLog(log)
+
+			var buf bytes.Buffer
+			if err := ls.WriteCSV(&buf); err != nil {
+				t.Fatalf("WriteCSV() error = %v", err)
+			}
+
+			records, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatalf("reading CSV: %v", err)
+			}
+			want := [][]string{
+				{"timestamp", "namespace", "pod", "container", "content"},
+				{log.Timestamp, log.Namespace, log.PodName, log.Container, log.LogContent},
+			}
+			if !reflect.DeepEqual(records, want) {
+				t.Errorf("WriteCSV() records = %q, want %q", records, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"fmt"
+	"hallucino/internal/k8s"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// ParseTemplate parses a text/template that formats one entry per line, with
+// the k8s.LogEntry fields available, e.g.
+// "{{.Timestamp}} [{{.Container}}] {{.LogContent}}". The template is tried on
+// an empty entry so references to fields that don't exist fail here rather
+// than on every line.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("line").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, k8s.LogEntry{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// SetTemplate replaces the built-in line format of pretty printing with a
+// template from ParseTemplate. Lines aren't colored or highlighted. A nil
+// template restores the built-in format.
+func (ls *LogStorage) SetTemplate(tmpl *template.Template) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.template = tmpl
+}
+
+// templateLine formats an entry with the template, ending it with a newline
+// unless the template already does. Failures are reported in place of the line.
+func templateLine(tmpl *template.Template, log k8s.LogEntry) string {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, log); err != nil {
+		return fmt.Sprintf("[template error: %v]\n", err)
+	}
+	if !strings.HasSuffix(b.String(), "\n") {
+		b.WriteByte('\n')
+	}
+	return b.String()
+}